@@ -8,9 +8,9 @@ import (
 )
 
 func main() {
-	rootCmd, err := commands.InitRunCommand()
+	rootCmd, err := commands.InitRootCommand()
 	if err != nil {
-		fmt.Println("init run command: %w", err)
+		fmt.Println("init root command: %w", err)
 		os.Exit(1)
 	}
 	err = rootCmd.Execute()
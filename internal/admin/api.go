@@ -0,0 +1,248 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/inspect"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// API is json admin api to inspect and control one election of the node
+type API struct {
+	name       string
+	source     StatusSource
+	connector  backend.Connector
+	control    *states.Control
+	options    *cmdargs.Live
+	partitions PartitionSource
+	token      ControlToken
+}
+
+// ControlToken returns bearer token required by control endpoints, empty token disables them
+type ControlToken func() (string, error)
+
+type statusResponse struct {
+	NodeID         string               `json:"node_id"`
+	Election       string               `json:"election,omitempty"`
+	Namespace      string               `json:"namespace,omitempty"`
+	State          string               `json:"state"`
+	Since          time.Time            `json:"since"`
+	InStateSeconds float64              `json:"in_state_seconds"`
+	PreviousState  string               `json:"previous_state,omitempty"`
+	LastReason     string               `json:"last_reason,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	Leader         bool                 `json:"leader"`
+	SessionID      string               `json:"session_id,omitempty"`
+	Control        states.ControlStatus `json:"control"`
+}
+
+type leaderResponse struct {
+	inspect.LeaderInfo
+	IsSelf bool `json:"is_self"`
+}
+
+type memberResponse struct {
+	membership.Member
+	HeartbeatAgeSeconds float64 `json:"heartbeat_age_seconds"`
+	Stale               bool    `json:"stale"`
+	IsSelf              bool    `json:"is_self"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// RegisterAPI adds /api/v1 handlers to the server. Handlers of named elections are served
+// under /api/v1/elections/{name}, GET /api/v1/elections lists statuses of all of them.
+// Handlers which change the election state require token, they are disabled without it.
+func RegisterAPI(s *Server, elections []Election, token ControlToken) {
+	if single(elections) {
+		registerElectionAPI(s, "/api/v1", elections[0], token)
+		return
+	}
+	apis := make([]*API, 0, len(elections))
+	for _, e := range elections {
+		apis = append(apis, registerElectionAPI(s, "/api/v1/elections/"+e.Name, e, token))
+	}
+	s.Handle("GET /api/v1/elections", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		resp := make([]statusResponse, 0, len(apis))
+		for _, api := range apis {
+			resp = append(resp, api.statusResponse())
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}))
+}
+
+func registerElectionAPI(s *Server, prefix string, e Election, token ControlToken) *API {
+	api := &API{name: e.Name, source: e.Source, connector: e.Connector, control: e.Control, options: e.Options, partitions: e.Partitions, token: token}
+	s.Handle("GET "+prefix+"/status", http.HandlerFunc(api.status))
+	s.Handle("GET "+prefix+"/leader", http.HandlerFunc(api.leader))
+	s.Handle("GET "+prefix+"/config", http.HandlerFunc(api.config))
+	s.Handle("GET "+prefix+"/storage", http.HandlerFunc(api.storage))
+	s.Handle("GET "+prefix+"/members", http.HandlerFunc(api.members))
+	s.Handle("POST "+prefix+"/resign", api.authorized(api.resign))
+	s.Handle("POST "+prefix+"/pause", api.authorized(api.pause))
+	s.Handle("POST "+prefix+"/resume", api.authorized(api.resume))
+	if e.Partitions != nil {
+		s.Handle("GET "+prefix+"/partitions", http.HandlerFunc(api.partitionStatus))
+	}
+	return api
+}
+
+// authorized passes only requests with bearer token, anyone who reaches admin address
+// must not be able to take leadership away
+func (a *API) authorized(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := a.token()
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+			return
+		}
+		if token == "" {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "control endpoints are disabled, set admin-control-token-file to enable them"})
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "bad or missing bearer token"})
+			return
+		}
+		h(w, r)
+	})
+}
+
+func (a *API) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.statusResponse())
+}
+
+func (a *API) statusResponse() statusResponse {
+	st := a.source.Status()
+	opts := a.options.Get()
+	resp := statusResponse{
+		NodeID:         opts.NodeID,
+		Election:       a.name,
+		Namespace:      opts.Namespace,
+		State:          st.State,
+		Since:          st.Since,
+		InStateSeconds: time.Since(st.Since).Seconds(),
+		PreviousState:  st.Previous,
+		LastReason:     st.Reason,
+		Error:          st.Error,
+		Leader:         st.Leader,
+		Control:        a.control.Status(),
+	}
+	if conn := a.connector.Current(); conn != nil {
+		resp.SessionID = fmt.Sprintf("0x%x", conn.SessionID())
+	}
+	return resp
+}
+
+func (a *API) leader(w http.ResponseWriter, _ *http.Request) {
+	conn := a.connector.Current()
+	if conn == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "no zookeeper connection"})
+		return
+	}
+	info, err := inspect.Leader(conn, a.options.Get().ElectionFileDir)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, leaderResponse{LeaderInfo: info, IsSelf: info.Present && info.SessionID == conn.SessionID()})
+}
+
+func (a *API) config(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.options.Get().Values())
+}
+
+func (a *API) storage(w http.ResponseWriter, _ *http.Request) {
+	conn := a.connector.Current()
+	if conn == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "no zookeeper connection"})
+		return
+	}
+	files, err := inspect.Ring(conn, a.options.Get().LeaderFileDir, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (a *API) members(w http.ResponseWriter, _ *http.Request) {
+	conn := a.connector.Current()
+	if conn == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "no zookeeper connection"})
+		return
+	}
+	opts := a.options.Get()
+	members, err := membership.List(conn, opts.MembersDir)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+		return
+	}
+	now := time.Now()
+	resp := make([]memberResponse, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, memberResponse{
+			Member:              m,
+			HeartbeatAgeSeconds: now.Sub(m.Heartbeat).Seconds(),
+			Stale:               m.Stale(now, opts.HeartbeatInterval),
+			IsSelf:              m.SessionID == conn.SessionID(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// resign makes leader give up leadership, node doesn't contest for cooldown query param or 3 attempter timeouts.
+// Leader resigns before min leader tenure ends only with force query param.
+func (a *API) resign(w http.ResponseWriter, r *http.Request) {
+	st := a.source.Status()
+	if !st.Leader {
+		writeJSON(w, http.StatusConflict, errorResponse{Error: "node is not leader"})
+		return
+	}
+	if tenureEnd := st.Since.Add(a.options.Get().MinLeaderTenure); time.Now().Before(tenureEnd) && r.URL.Query().Get("force") != "true" {
+		writeJSON(w, http.StatusConflict, errorResponse{Error: fmt.Sprintf("leader tenure lasts until %s, use force=true to resign earlier", tenureEnd.Format(time.RFC3339))})
+		return
+	}
+	cooldown := 3 * a.options.Get().AttempterTimeout
+	if val := r.URL.Query().Get("cooldown"); val != "" {
+		var err error
+		if cooldown, err = time.ParseDuration(val); err != nil || cooldown < 0 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("bad cooldown %q", val)})
+			return
+		}
+	}
+	a.control.Resign(time.Now(), cooldown)
+	writeJSON(w, http.StatusAccepted, a.control.Status())
+}
+
+func (a *API) pause(w http.ResponseWriter, _ *http.Request) {
+	a.control.Pause()
+	writeJSON(w, http.StatusOK, a.control.Status())
+}
+
+func (a *API) resume(w http.ResponseWriter, _ *http.Request) {
+	a.control.Resume()
+	writeJSON(w, http.StatusOK, a.control.Status())
+}
+
+func (a *API) partitionStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.partitions.Status())
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+type leaderSource struct{}
+
+func (leaderSource) Status() run.Status {
+	return run.Status{State: "LeaderState", Leader: true, Since: time.Now().Add(-time.Hour)}
+}
+
+func newTestAPI(token ControlToken) (http.Handler, *states.Control) {
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), "", "", "")
+	control := states.NewControl()
+	RegisterAPI(s, []Election{{
+		Source:    leaderSource{},
+		Connector: &backend.FakeConnector{Server: backend.NewFakeServer()},
+		Control:   control,
+		Options:   cmdargs.NewLive(cmdargs.RunArgs{AttempterTimeout: time.Second}),
+	}}, token)
+	return s.mux, control
+}
+
+func staticToken(token string) ControlToken {
+	return func() (string, error) { return token, nil }
+}
+
+func do(h http.Handler, method, target, auth string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestControlRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		token ControlToken
+		auth  string
+		code  int
+	}{
+		{"disabled", staticToken(""), "Bearer secret", http.StatusForbidden},
+		{"missing token", staticToken("secret"), "", http.StatusUnauthorized},
+		{"wrong token", staticToken("secret"), "Bearer secreT", http.StatusUnauthorized},
+		{"token prefix", staticToken("secret"), "Bearer secre", http.StatusUnauthorized},
+		{"not bearer", staticToken("secret"), "Basic secret", http.StatusUnauthorized},
+		{"unreadable token file", func() (string, error) { return "", errors.New("read admin control token file: denied") }, "Bearer secret", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, control := newTestAPI(tt.token)
+			for _, pth := range []string{"/api/v1/resign", "/api/v1/pause", "/api/v1/resume"} {
+				if w := do(h, http.MethodPost, pth, tt.auth); w.Code != tt.code {
+					t.Errorf("POST %s = %d %s, want %d", pth, w.Code, w.Body, tt.code)
+				}
+			}
+			if st := control.Status(); st.Paused || st.HoldUntil != nil {
+				t.Fatalf("rejected request changed control: %+v", st)
+			}
+			select {
+			case <-control.Resigned():
+				t.Fatal("rejected request made leader resign")
+			default:
+			}
+			// inspection doesn't need token
+			if w := do(h, http.MethodGet, "/api/v1/status", ""); w.Code != http.StatusOK {
+				t.Fatalf("GET status = %d, want 200", w.Code)
+			}
+		})
+	}
+}
+
+func TestControlAllowed(t *testing.T) {
+	h, control := newTestAPI(staticToken("secret"))
+
+	if w := do(h, http.MethodPost, "/api/v1/pause", "Bearer secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"paused":true`) {
+		t.Fatalf("pause = %d %s", w.Code, w.Body)
+	}
+	if !control.Status().Paused {
+		t.Fatal("node isn't paused")
+	}
+	if w := do(h, http.MethodPost, "/api/v1/resume", "Bearer secret"); w.Code != http.StatusOK || control.Status().Paused {
+		t.Fatalf("resume = %d %s", w.Code, w.Body)
+	}
+	if w := do(h, http.MethodPost, "/api/v1/resign?cooldown=1m", "Bearer secret"); w.Code != http.StatusAccepted {
+		t.Fatalf("resign = %d %s", w.Code, w.Body)
+	}
+	select {
+	case <-control.Resigned():
+	default:
+		t.Fatal("leader isn't asked to resign")
+	}
+}
@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/partition"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// StatusSource gives current automaton status, it's implemented by run.Runner
+type StatusSource interface {
+	Status() run.Status
+}
+
+// Election is an automaton served by admin server
+type Election struct {
+	Name      string // empty for the only election of the process
+	Source    StatusSource
+	Connector backend.Connector
+	Control   *states.Control
+	Options   *cmdargs.Live
+	// Partitions is nil if partitioning is disabled
+	Partitions PartitionSource
+}
+
+// PartitionSource gives partitions owned by the node, it's implemented by partition.Owner
+type PartitionSource interface {
+	Status() partition.Status
+}
+
+// single reports whether process runs the only unnamed election, it's served without election prefixes
+func single(elections []Election) bool {
+	return len(elections) == 1 && elections[0].Name == ""
+}
+
+type healthResponse struct {
+	Status  string `json:"status"`
+	State   string `json:"state"`
+	InState string `json:"in_state"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type aggregateHealthResponse struct {
+	Status    string                    `json:"status"`
+	Elections map[string]healthResponse `json:"elections"`
+}
+
+// probe returns the reason of probe failure, empty if it succeeds
+type probe func(e Election, st run.Status) string
+
+// RegisterHealth adds kubernetes probes:
+// /healthz fails if automaton stopped or is stuck out of Attemper, Leader and Observer states for too long
+// or Attemper and Leader stopped making progress, e.g. hang on zookeeper call,
+// /readyz fails if node isn't leader or candidate depending on readiness option, observer is ready as candidate,
+// /leader succeeds only on the leader.
+// Probes of named elections are served under /elections/{name}/, root ones succeed only if all elections pass.
+func RegisterHealth(s *Server, elections []Election) {
+	probes := map[string]probe{
+		"/healthz": healthFailure,
+		"/readyz":  readyFailure,
+		"/leader":  leaderFailure,
+	}
+	for pth, check := range probes {
+		if single(elections) {
+			s.Handle(pth, probeHandler(elections[0], check))
+			continue
+		}
+		for _, e := range elections {
+			s.Handle("/elections/"+e.Name+pth, probeHandler(e, check))
+		}
+		s.Handle(pth, aggregateHandler(elections, check))
+	}
+}
+
+func healthFailure(e Election, st run.Status) string {
+	switch inState := time.Since(st.Since); {
+	case st.State == "" && st.Error != "":
+		return "automaton stopped: " + st.Error
+	case st.State == "":
+		return "automaton is not running"
+	case !st.Contending && !st.Observing && inState > e.Options.Get().StuckStateTimeout:
+		return "automaton is stuck in state"
+	case st.Contending && time.Since(st.Progress) > e.Options.Get().StuckStateTimeout:
+		return "automaton made no progress in state"
+	}
+	return ""
+}
+
+func readyFailure(e Election, st run.Status) string {
+	switch e.Options.Get().Readiness {
+	case cmdargs.ReadinessLeader:
+		if !st.Leader {
+			return "node is not leader"
+		}
+	default:
+		if !st.Contending && !st.Observing {
+			return "node is not contending for leadership"
+		}
+	}
+	return ""
+}
+
+func leaderFailure(_ Election, st run.Status) string {
+	if !st.Leader {
+		return "node is not leader"
+	}
+	return ""
+}
+
+func probeHandler(e Election, check probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		st := e.Source.Status()
+		writeHealth(w, st, check(e, st))
+	})
+}
+
+func aggregateHandler(elections []Election, check probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		resp := aggregateHealthResponse{Status: "ok", Elections: map[string]healthResponse{}}
+		code := http.StatusOK
+		for _, e := range elections {
+			st := e.Source.Status()
+			hr := newHealthResponse(st, check(e, st))
+			if hr.Reason != "" {
+				resp.Status = "fail"
+				code = http.StatusServiceUnavailable
+			}
+			resp.Elections[e.Name] = hr
+		}
+		writeJSON(w, code, resp)
+	})
+}
+
+func newHealthResponse(st run.Status, reason string) healthResponse {
+	resp := healthResponse{
+		Status:  "ok",
+		State:   st.State,
+		InState: time.Since(st.Since).Round(time.Millisecond).String(),
+		Reason:  reason,
+	}
+	if reason != "" {
+		resp.Status = "fail"
+	}
+	return resp
+}
+
+// writeHealth writes 200 if there is no failure reason and 503 otherwise
+func writeHealth(w http.ResponseWriter, st run.Status, reason string) {
+	code := http.StatusOK
+	if reason != "" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, newHealthResponse(st, reason))
+}
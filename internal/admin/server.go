@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// shutdownTimeout limits graceful shutdown, it's counted from app stop so app context can't be used for it
+const shutdownTimeout = 5 * time.Second
+
+// Server is http server for metrics and admin handlers, it has its own mux
+// so handlers registered on http.DefaultServeMux by libraries don't leak on it
+type Server struct {
+	logger   *slog.Logger
+	mux      *http.ServeMux
+	srv      *http.Server
+	certFile string
+	keyFile  string
+}
+
+// New creates server listening addr, it serves https if both certFile and keyFile are set
+func New(logger *slog.Logger, addr, certFile, keyFile string) *Server {
+	logger = logger.With("subsystem", "AdminServer")
+	mux := http.NewServeMux()
+	return &Server{
+		logger:   logger,
+		mux:      mux,
+		srv:      &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run starts serving in eg and shuts server down when ctx is done
+func (s *Server) Run(ctx context.Context, eg *errgroup.Group) {
+	eg.Go(func() error {
+		<-ctx.Done()
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Admin http server is shutting down")
+		shCtx, cncl := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cncl()
+		return s.srv.Shutdown(shCtx)
+	})
+
+	eg.Go(func() error {
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Admin http server is starting", slog.String("addr", s.srv.Addr), slog.Bool("tls", s.certFile != ""))
+		var err error
+		if s.certFile != "" {
+			err = s.srv.ListenAndServeTLS(s.certFile, s.keyFile)
+		} else {
+			err = s.srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.LogAttrs(ctx, slog.LevelError, "Admin http server fell down: "+err.Error())
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,21 @@
+package backend
+
+import (
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/go-zookeeper/zk"
+)
+
+// digestScheme is zookeeper auth scheme of user:password credentials
+const digestScheme = "digest"
+
+// ACL returns ACL of nodes created by the node, creator modes need authenticated session
+func ACL(mode string) []zk.ACL {
+	switch mode {
+	case cmdargs.ACLCreatorRead:
+		return append(zk.AuthACL(zk.PermAll), zk.WorldACL(zk.PermRead)...)
+	case cmdargs.ACLCreator:
+		return zk.AuthACL(zk.PermAll)
+	default:
+		return zk.WorldACL(zk.PermAll)
+	}
+}
@@ -0,0 +1,179 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/go-zookeeper/zk"
+)
+
+var (
+	_ Connector = &ZkConnector{}
+	_ Conn      = &zkConn{}
+)
+
+// Conn is the part of zookeeper client used by states
+type Conn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Delete(path string, version int32) error
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	GetACL(path string) ([]zk.ACL, *zk.Stat, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Exists(path string) (bool, *zk.Stat, error)
+	// ExistsW and ChildrenW also set one-shot watch, it fires on node or children list change
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	SessionID() int64
+	State() zk.State
+	// SessionTimeout returns timeout negotiated with zookeeper server, it can differ from the requested one
+	SessionTimeout() time.Duration
+	Close()
+}
+
+// Connector opens new zookeeper sessions
+type Connector interface {
+	Connect(ctx context.Context) (Conn, error)
+	// Current returns the last opened connection, nil if there were none
+	Current() Conn
+}
+
+func NewZkConnector(logger *slog.Logger, opts *cmdargs.Live, onEvent func(zk.Event)) *ZkConnector {
+	return &ZkConnector{
+		logger:  logger,
+		options: opts,
+		onEvent: onEvent,
+	}
+}
+
+type ZkConnector struct {
+	logger  *slog.Logger
+	options *cmdargs.Live
+	onEvent func(zk.Event)
+	current atomic.Pointer[zkConn]
+}
+
+func (c *ZkConnector) Current() Conn {
+	if conn := c.current.Load(); conn != nil {
+		return conn
+	}
+	return nil
+}
+
+// Connect connects to zookeeper and waits until session is established or session timeout passes
+func (c *ZkConnector) Connect(ctx context.Context) (Conn, error) {
+	opts := c.options.Get()
+	creds, err := opts.ZkCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if opts.ZkTLS { // broken tls files fail every dial, report them instead of session timeout
+		if _, err := tlsConfig(opts, opts.ZookeeperServers[0]); err != nil {
+			return nil, err
+		}
+	}
+	conn := &zkConn{negotiated: make(chan struct{})}
+	conn.sessionTimeout.Store(int64(opts.SessionTimeout))
+
+	hosts := zk.WithHostProvider(&zk.DNSHostProvider{})
+	if opts.ZkTLS { // server name of tls is taken from the dialed address, so it must stay a host name
+		hosts = zk.WithHostProvider(&hostProvider{})
+	}
+	zkConn, events, err := zk.Connect(opts.ZookeeperServers, opts.SessionTimeout,
+		zk.WithLogger(&zkLogger{logger: c.logger, conn: conn}),
+		zk.WithDialer(dialer(opts)),
+		hosts,
+		zk.WithEventCallback(func(ev zk.Event) {
+			if c.onEvent != nil {
+				c.onEvent(ev)
+			}
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("zk connect: %w", err)
+	}
+	conn.Conn = zkConn
+
+	timer := time.NewTimer(opts.SessionTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return nil, zk.ErrConnectionClosed
+			}
+			if ev.State == zk.StateHasSession {
+				// zk.Conn reports session before logging negotiated timeout, give it a chance to come
+				select {
+				case <-conn.negotiated:
+				case <-timer.C:
+					// log format of the library may change, then leader timings are checked against the requested timeout
+					c.logger.LogAttrs(ctx, slog.LevelWarn, "Negotiated session timeout not found in zookeeper client log, requested one is used",
+						slog.Duration("requested_session_timeout", opts.SessionTimeout))
+				}
+				// credentials are kept by zk.Conn and sent again on reconnect within the session
+				if creds != "" {
+					if err := zkConn.AddAuth(digestScheme, []byte(creds)); err != nil {
+						zkConn.Close()
+						return nil, fmt.Errorf("zk auth: %w", err)
+					}
+				}
+				c.logger.LogAttrs(ctx, slog.LevelInfo, "Zookeeper session established",
+					slog.Int64("session_id", zkConn.SessionID()),
+					slog.Duration("requested_session_timeout", opts.SessionTimeout),
+					slog.Duration("negotiated_session_timeout", conn.SessionTimeout()),
+					slog.Bool("authenticated", creds != ""))
+				c.current.Store(conn)
+				return conn, nil
+			}
+		case <-timer.C:
+			zkConn.Close()
+			return nil, fmt.Errorf("session not established in %s: %w", opts.SessionTimeout, zk.ErrNoServer)
+		case <-ctx.Done():
+			zkConn.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// zkConn is a zookeeper connection which also knows session timeout negotiated with the server
+type zkConn struct {
+	*zk.Conn
+	sessionTimeout atomic.Int64
+	negotiated     chan struct{}
+	negotiatedOnce sync.Once
+}
+
+func (c *zkConn) SessionTimeout() time.Duration {
+	return time.Duration(c.sessionTimeout.Load())
+}
+
+// zkLogger routes zk library logs to slog and catches negotiated session timeout,
+// which zk.Conn keeps private and reports only in its log
+type zkLogger struct {
+	logger *slog.Logger
+	conn   *zkConn
+}
+
+func (l *zkLogger) Printf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if timeout, ok := parseSessionTimeout(msg); ok {
+		l.conn.sessionTimeout.Store(int64(timeout))
+		l.conn.negotiatedOnce.Do(func() { close(l.conn.negotiated) })
+	}
+	l.logger.LogAttrs(context.Background(), slog.LevelDebug, msg, slog.String("subsystem", "ZkClient"))
+}
+
+// parseSessionTimeout extracts negotiated session timeout from the line zk.Conn logs after authentication
+func parseSessionTimeout(msg string) (time.Duration, bool) {
+	var id int64
+	var timeoutMs int32
+	if _, err := fmt.Sscanf(msg, "authenticated: id=%d, timeout=%d", &id, &timeoutMs); err != nil || timeoutMs <= 0 {
+		return 0, false
+	}
+	return time.Duration(timeoutMs) * time.Millisecond, true
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestParseSessionTimeout(t *testing.T) {
+	tests := []struct {
+		msg     string
+		timeout time.Duration
+		ok      bool
+	}{
+		{"authenticated: id=72057594037927936, timeout=4000", 4 * time.Second, true},
+		{"authenticated: id=1, timeout=40000", 40 * time.Second, true},
+		{"authenticated: id=-1, timeout=6000", 6 * time.Second, true},
+		{"authenticated: id=1, timeout=0", 0, false},
+		{"connected to 127.0.0.1:2181", 0, false},
+		{"authentication failed: zk: session has been expired by the server", 0, false},
+		{"authenticated: id=1, session_timeout=4000", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		timeout, ok := parseSessionTimeout(tt.msg)
+		if timeout != tt.timeout || ok != tt.ok {
+			t.Errorf("parseSessionTimeout(%q) = %s, %t, expected %s, %t", tt.msg, timeout, ok, tt.timeout, tt.ok)
+		}
+	}
+}
+
+// TestZkLoggerNegotiatedTimeout checks the line actually logged by zk.Conn, so library update changing it fails here
+func TestZkLoggerNegotiatedTimeout(t *testing.T) {
+	conn := &zkConn{negotiated: make(chan struct{})}
+	conn.sessionTimeout.Store(int64(time.Second))
+	l := &zkLogger{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), conn: conn}
+
+	l.Printf("connected to %s", "127.0.0.1:2181")
+	select {
+	case <-conn.negotiated:
+		t.Fatal("negotiated by unrelated line")
+	default:
+	}
+
+	l.Printf("authenticated: id=%d, timeout=%d", int64(42), int32(2500))
+	select {
+	case <-conn.negotiated:
+	default:
+		t.Fatal("not negotiated")
+	}
+	if conn.SessionTimeout() != 2500*time.Millisecond {
+		t.Fatalf("expected 2.5s, got %s", conn.SessionTimeout())
+	}
+}
@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var errorClasses = []struct {
+	err   error
+	class string
+}{
+	{zk.ErrNodeExists, "node_exists"},
+	{zk.ErrNoNode, "no_node"},
+	{zk.ErrNotEmpty, "not_empty"},
+	{zk.ErrBadVersion, "bad_version"},
+	{zk.ErrNoAuth, "no_auth"},
+	{zk.ErrAuthFailed, "auth_failed"},
+	{zk.ErrInvalidACL, "invalid_acl"},
+	{zk.ErrConnectionClosed, "connection_closed"},
+	{zk.ErrSessionExpired, "session_expired"},
+	{zk.ErrSessionMoved, "session_moved"},
+	{zk.ErrNoServer, "no_server"},
+	{zk.ErrClosing, "closing"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+}
+
+// ErrorClass maps error to a short stable name usable as metric label
+func ErrorClass(err error) string {
+	if err == nil {
+		return "none"
+	}
+	for _, ec := range errorClasses {
+		if errors.Is(err, ec.err) {
+			return ec.class
+		}
+	}
+	return "other"
+}
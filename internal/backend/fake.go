@@ -0,0 +1,424 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var (
+	_ Connector = &FakeConnector{}
+	_ Conn      = &FakeConn{}
+)
+
+// FakeOp is a write applied by FakeServer, tests read them to know what states did
+type FakeOp struct {
+	Op        string // create, set or delete
+	Path      string
+	SessionID int64
+}
+
+// FakeServer is an in-memory zookeeper for tests: sessions share one tree, ephemeral nodes die with
+// their session, versions are checked and one-shot watches fire like on the real server.
+type FakeServer struct {
+	mu       sync.Mutex
+	nodes    map[string]*fakeNode
+	zxid     int64
+	sessions int64
+	watches  map[string][]fakeWatch
+	ops      chan FakeOp
+}
+
+type fakeNode struct {
+	data []byte
+	acl  []zk.ACL
+	stat zk.Stat
+}
+
+type fakeWatch struct {
+	conn     *FakeConn
+	ch       chan zk.Event
+	children bool
+}
+
+func NewFakeServer() *FakeServer {
+	return &FakeServer{
+		nodes:   map[string]*fakeNode{"/": {}},
+		watches: map[string][]fakeWatch{},
+	}
+}
+
+// Record starts passing writes to the returned channel, writers block until test reads them
+func (s *FakeServer) Record() <-chan FakeOp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = make(chan FakeOp, 100)
+	return s.ops
+}
+
+// Connect opens new session
+func (s *FakeServer) Connect() *FakeConn {
+	return &FakeConn{
+		server:  s,
+		id:      atomic.AddInt64(&s.sessions, 1),
+		timeout: 4 * time.Second,
+	}
+}
+
+// Paths returns all nodes except root in lexical order
+func (s *FakeServer) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, 0, len(s.nodes))
+	for pth := range s.nodes {
+		if pth != "/" {
+			res = append(res, pth)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (s *FakeServer) record(op, pth string, session int64) {
+	if s.ops != nil {
+		s.ops <- FakeOp{Op: op, Path: pth, SessionID: session}
+	}
+}
+
+func parentPath(pth string) string {
+	i := strings.LastIndex(pth, "/")
+	if i == 0 {
+		return "/"
+	}
+	return pth[:i]
+}
+
+func validPath(pth string) bool {
+	return pth == "/" || (strings.HasPrefix(pth, "/") && !strings.HasSuffix(pth, "/") && !strings.Contains(pth, "//"))
+}
+
+func (s *FakeServer) children(pth string) []string {
+	prefix := pth + "/"
+	if pth == "/" {
+		prefix = "/"
+	}
+	var res []string
+	for p := range s.nodes {
+		if p != "/" && strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			res = append(res, p[len(prefix):])
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// fire triggers watches of the path, children watches fire only on children events and node deletion
+func (s *FakeServer) fire(pth string, typ zk.EventType) {
+	var left []fakeWatch
+	for _, w := range s.watches[pth] {
+		if w.children != (typ == zk.EventNodeChildrenChanged) && typ != zk.EventNodeDeleted {
+			left = append(left, w)
+			continue
+		}
+		w.ch <- zk.Event{Type: typ, State: zk.StateHasSession, Path: pth}
+		close(w.ch)
+	}
+	s.watches[pth] = left
+}
+
+func (s *FakeServer) create(c *FakeConn, pth string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	if !validPath(pth) || pth == "/" {
+		return "", zk.ErrInvalidPath
+	}
+	parent, ok := s.nodes[parentPath(pth)]
+	if !ok {
+		return "", zk.ErrNoNode
+	}
+	if parent.stat.EphemeralOwner != 0 {
+		return "", zk.ErrNoChildrenForEphemerals
+	}
+	if flags&zk.FlagSequence != 0 {
+		pth = fmt.Sprintf("%s%010d", pth, parent.stat.Cversion)
+	}
+	if _, ok := s.nodes[pth]; ok {
+		return "", zk.ErrNodeExists
+	}
+	s.zxid++
+	node := &fakeNode{data: data, acl: acl}
+	node.stat = zk.Stat{Czxid: s.zxid, Mzxid: s.zxid, Pzxid: s.zxid, DataLength: int32(len(data))}
+	if flags&zk.FlagEphemeral != 0 {
+		node.stat.EphemeralOwner = c.id
+	}
+	s.nodes[pth] = node
+	parent.stat.Cversion++
+	parent.stat.NumChildren++
+	parent.stat.Pzxid = s.zxid
+	s.record("create", pth, c.id)
+	s.fire(pth, zk.EventNodeCreated)
+	s.fire(parentPath(pth), zk.EventNodeChildrenChanged)
+	return pth, nil
+}
+
+func (s *FakeServer) delete(c *FakeConn, pth string, version int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	return s.remove(c.id, pth, version)
+}
+
+func (s *FakeServer) remove(session int64, pth string, version int32) error {
+	node, ok := s.nodes[pth]
+	if !ok || pth == "/" {
+		return zk.ErrNoNode
+	}
+	if version != -1 && version != node.stat.Version {
+		return zk.ErrBadVersion
+	}
+	if node.stat.NumChildren > 0 {
+		return zk.ErrNotEmpty
+	}
+	s.zxid++
+	delete(s.nodes, pth)
+	parent := s.nodes[parentPath(pth)]
+	parent.stat.Cversion++
+	parent.stat.NumChildren--
+	parent.stat.Pzxid = s.zxid
+	s.record("delete", pth, session)
+	s.fire(pth, zk.EventNodeDeleted)
+	s.fire(parentPath(pth), zk.EventNodeChildrenChanged)
+	return nil
+}
+
+func (s *FakeServer) set(c *FakeConn, pth string, data []byte, version int32) (*zk.Stat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	node, ok := s.nodes[pth]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != node.stat.Version {
+		return nil, zk.ErrBadVersion
+	}
+	s.zxid++
+	node.data = data
+	node.stat.Version++
+	node.stat.Mzxid = s.zxid
+	node.stat.DataLength = int32(len(data))
+	s.record("set", pth, c.id)
+	s.fire(pth, zk.EventNodeDataChanged)
+	stat := node.stat
+	return &stat, nil
+}
+
+func (s *FakeServer) get(c *FakeConn, pth string) (*fakeNode, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	node, ok := s.nodes[pth]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	return node, nil
+}
+
+func (s *FakeServer) watch(c *FakeConn, pth string, children bool) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	s.watches[pth] = append(s.watches[pth], fakeWatch{conn: c, ch: ch, children: children})
+	return ch
+}
+
+// closeSession removes ephemeral nodes of the session and stops its watches
+func (s *FakeServer) closeSession(c *FakeConn, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	for pth, watches := range s.watches {
+		var left []fakeWatch
+		for _, w := range watches {
+			if w.conn != c {
+				left = append(left, w)
+				continue
+			}
+			w.ch <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: pth, Err: err}
+			close(w.ch)
+		}
+		s.watches[pth] = left
+	}
+	var owned []string
+	for pth, node := range s.nodes {
+		if node.stat.EphemeralOwner == c.id {
+			owned = append(owned, pth)
+		}
+	}
+	sort.Strings(owned)
+	for _, pth := range owned {
+		_ = s.remove(c.id, pth, -1)
+	}
+}
+
+// FakeConn is a session of FakeServer
+type FakeConn struct {
+	server  *FakeServer
+	id      int64
+	timeout time.Duration
+	err     error // set when session is closed or expired, guarded by server mutex
+}
+
+func (c *FakeConn) check() error {
+	return c.err
+}
+
+func (c *FakeConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	return c.server.create(c, path, data, flags, acl)
+}
+
+func (c *FakeConn) Delete(path string, version int32) error {
+	return c.server.delete(c, path, version)
+}
+
+func (c *FakeConn) Children(path string) ([]string, *zk.Stat, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat := node.stat
+	return c.server.children(path), &stat, nil
+}
+
+func (c *FakeConn) Get(path string) ([]byte, *zk.Stat, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat := node.stat
+	return append([]byte(nil), node.data...), &stat, nil
+}
+
+func (c *FakeConn) GetACL(path string) ([]zk.ACL, *zk.Stat, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat := node.stat
+	return node.acl, &stat, nil
+}
+
+func (c *FakeConn) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	return c.server.set(c, path, data, version)
+}
+
+func (c *FakeConn) Exists(path string) (bool, *zk.Stat, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err == zk.ErrNoNode {
+		return false, &zk.Stat{}, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	stat := node.stat
+	return true, &stat, nil
+}
+
+func (c *FakeConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err == zk.ErrNoNode {
+		return false, &zk.Stat{}, c.server.watch(c, path, false), nil
+	}
+	if err != nil {
+		return false, nil, nil, err
+	}
+	stat := node.stat
+	return true, &stat, c.server.watch(c, path, false), nil
+}
+
+func (c *FakeConn) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	node, err := c.server.get(c, path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stat := node.stat
+	return c.server.children(path), &stat, c.server.watch(c, path, true), nil
+}
+
+func (c *FakeConn) SessionID() int64 {
+	return c.id
+}
+
+func (c *FakeConn) State() zk.State {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.err != nil {
+		return zk.StateDisconnected
+	}
+	return zk.StateHasSession
+}
+
+func (c *FakeConn) SessionTimeout() time.Duration {
+	return c.timeout
+}
+
+func (c *FakeConn) Close() {
+	c.server.closeSession(c, zk.ErrClosing)
+}
+
+// Expire ends the session as server does when client doesn't ping it for session timeout
+func (c *FakeConn) Expire() {
+	c.server.closeSession(c, zk.ErrSessionExpired)
+}
+
+// FakeConnector opens sessions of FakeServer, connection fails with Err if it's set
+type FakeConnector struct {
+	Server  *FakeServer
+	Err     error
+	current atomic.Pointer[FakeConn]
+}
+
+func (c *FakeConnector) Connect(ctx context.Context) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	conn := c.Server.Connect()
+	c.current.Store(conn)
+	return conn, nil
+}
+
+func (c *FakeConnector) Current() Conn {
+	if conn := c.current.Load(); conn != nil {
+		return conn
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-zookeeper/zk"
+)
+
+func event(ch <-chan zk.Event) (zk.Event, bool) {
+	select {
+	case ev, ok := <-ch:
+		return ev, ok
+	default:
+		return zk.Event{}, false
+	}
+}
+
+func TestFakeServerVersions(t *testing.T) {
+	conn := NewFakeServer().Connect()
+	if _, err := conn.Create("/a/b", nil, 0, nil); !errors.Is(err, zk.ErrNoNode) {
+		t.Fatalf("create without parent: %v, want ErrNoNode", err)
+	}
+	if _, err := conn.Create("/a", nil, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Create("/a", nil, 0, nil); !errors.Is(err, zk.ErrNodeExists) {
+		t.Fatalf("second create: %v, want ErrNodeExists", err)
+	}
+	if _, err := conn.Create("/a/b", []byte("x"), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Delete("/a", -1); !errors.Is(err, zk.ErrNotEmpty) {
+		t.Fatalf("delete of parent: %v, want ErrNotEmpty", err)
+	}
+
+	stat, err := conn.Set("/a/b", []byte("y"), 0)
+	if err != nil || stat.Version != 1 {
+		t.Fatalf("set = %v, %v, want version 1", stat, err)
+	}
+	if err := conn.Delete("/a/b", 0); !errors.Is(err, zk.ErrBadVersion) {
+		t.Fatalf("delete of old version: %v, want ErrBadVersion", err)
+	}
+	if err := conn.Delete("/a/b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := conn.Exists("/a/b"); ok || err != nil {
+		t.Fatalf("exists after delete = %t, %v", ok, err)
+	}
+
+	seq, err := conn.Create("/a/n-", nil, zk.FlagSequence, nil)
+	if err != nil || seq != "/a/n-0000000002" {
+		t.Fatalf("sequential create = %q, %v, want parent cversion suffix", seq, err)
+	}
+}
+
+func TestFakeServerSessions(t *testing.T) {
+	srv := NewFakeServer()
+	first, second := srv.Connect(), srv.Connect()
+	if _, err := first.Create("/leader", nil, zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Create("/leader/child", nil, 0, nil); !errors.Is(err, zk.ErrNoChildrenForEphemerals) {
+		t.Fatalf("child of ephemeral: %v, want ErrNoChildrenForEphemerals", err)
+	}
+	_, stat, _, err := second.ExistsW("/leader")
+	if err != nil || stat.EphemeralOwner != first.SessionID() {
+		t.Fatalf("owner = %v, %v, want first session", stat, err)
+	}
+	_, _, chld, err := second.ChildrenW("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, own, err := first.ExistsW("/leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Expire()
+	if _, err := first.Create("/other", nil, 0, nil); !errors.Is(err, zk.ErrSessionExpired) {
+		t.Fatalf("create in expired session: %v, want ErrSessionExpired", err)
+	}
+	if got := srv.Paths(); len(got) != 0 {
+		t.Fatalf("paths after expiry = %v, want ephemeral node removed", got)
+	}
+	if ev, ok := event(own); !ok || ev.Type != zk.EventNotWatching {
+		t.Fatalf("own watch event = %+v, %t, want EventNotWatching", ev, ok)
+	}
+
+	ops := srv.Record()
+	if _, err := second.Create("/leader", nil, zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	if op := <-ops; !reflect.DeepEqual(op, FakeOp{Op: "create", Path: "/leader", SessionID: second.SessionID()}) {
+		t.Fatalf("recorded op = %+v", op)
+	}
+	if ev, ok := event(chld); !ok || ev.Type != zk.EventNodeChildrenChanged || ev.Path != "/" {
+		t.Fatalf("children watch event = %+v, %t, want the one of deletion", ev, ok)
+	}
+	if _, ok := event(chld); ok {
+		t.Fatal("children watch fired twice, it's one-shot")
+	}
+}
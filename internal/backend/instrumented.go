@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/go-zookeeper/zk"
+)
+
+var (
+	_ Connector = &InstrumentedConnector{}
+	_ Conn      = &instrumentedConn{}
+)
+
+// InstrumentedConnector records metrics of connects and of every operation of connections it opens
+type InstrumentedConnector struct {
+	connector Connector
+	metrics   *metrics.Metrics
+}
+
+func NewInstrumentedConnector(connector Connector, metrics *metrics.Metrics) *InstrumentedConnector {
+	return &InstrumentedConnector{
+		connector: connector,
+		metrics:   metrics,
+	}
+}
+
+func (c *InstrumentedConnector) Connect(ctx context.Context) (conn Conn, err error) {
+	defer observe(c.metrics, "connect")(&err)
+	if conn, err = c.connector.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{conn: conn, metrics: c.metrics}, nil
+}
+
+func (c *InstrumentedConnector) Current() Conn {
+	if conn := c.connector.Current(); conn != nil {
+		return &instrumentedConn{conn: conn, metrics: c.metrics}
+	}
+	return nil
+}
+
+// ObserveSessionEvents returns zk event callback which tracks session state and client reconnects
+func ObserveSessionEvents(m *metrics.Metrics) func(zk.Event) {
+	var mu sync.Mutex
+	var last zk.State = zk.StateUnknown
+	return func(ev zk.Event) {
+		if ev.Type != zk.EventSession {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ev.State == zk.StateConnecting && last != zk.StateUnknown {
+			m.ZkReconnects.Inc()
+		}
+		m.ZkSessionState.WithLabelValues(last.String()).Set(0)
+		m.ZkSessionState.WithLabelValues(ev.State.String()).Set(1)
+		last = ev.State
+	}
+}
+
+// observe starts measuring operation, returned func has to be deferred with pointer to operation error
+func observe(m *metrics.Metrics, op string) func(*error) {
+	start := time.Now()
+	m.ZkInFlight.WithLabelValues(op).Inc()
+	return func(err *error) {
+		m.ZkInFlight.WithLabelValues(op).Dec()
+		m.ZkOpDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			m.ZkOpErrors.WithLabelValues(op, ErrorClass(*err)).Inc()
+		}
+	}
+}
+
+type instrumentedConn struct {
+	conn    Conn
+	metrics *metrics.Metrics
+}
+
+func (c *instrumentedConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (res string, err error) {
+	defer observe(c.metrics, "create")(&err)
+	return c.conn.Create(path, data, flags, acl)
+}
+
+func (c *instrumentedConn) Delete(path string, version int32) (err error) {
+	defer observe(c.metrics, "delete")(&err)
+	return c.conn.Delete(path, version)
+}
+
+func (c *instrumentedConn) Children(path string) (res []string, stat *zk.Stat, err error) {
+	defer observe(c.metrics, "children")(&err)
+	return c.conn.Children(path)
+}
+
+func (c *instrumentedConn) Get(path string) (res []byte, stat *zk.Stat, err error) {
+	defer observe(c.metrics, "get")(&err)
+	return c.conn.Get(path)
+}
+
+func (c *instrumentedConn) GetACL(path string) (acl []zk.ACL, stat *zk.Stat, err error) {
+	defer observe(c.metrics, "get_acl")(&err)
+	return c.conn.GetACL(path)
+}
+
+func (c *instrumentedConn) Set(path string, data []byte, version int32) (stat *zk.Stat, err error) {
+	defer observe(c.metrics, "set")(&err)
+	return c.conn.Set(path, data, version)
+}
+
+func (c *instrumentedConn) Exists(path string) (res bool, stat *zk.Stat, err error) {
+	defer observe(c.metrics, "exists")(&err)
+	return c.conn.Exists(path)
+}
+
+func (c *instrumentedConn) ExistsW(path string) (res bool, stat *zk.Stat, ch <-chan zk.Event, err error) {
+	defer observe(c.metrics, "exists_watch")(&err)
+	return c.conn.ExistsW(path)
+}
+
+func (c *instrumentedConn) ChildrenW(path string) (res []string, stat *zk.Stat, ch <-chan zk.Event, err error) {
+	defer observe(c.metrics, "children_watch")(&err)
+	return c.conn.ChildrenW(path)
+}
+
+func (c *instrumentedConn) SessionID() int64 {
+	return c.conn.SessionID()
+}
+
+func (c *instrumentedConn) State() zk.State {
+	return c.conn.State()
+}
+
+func (c *instrumentedConn) SessionTimeout() time.Duration {
+	return c.conn.SessionTimeout()
+}
+
+func (c *instrumentedConn) Close() {
+	c.conn.Close()
+}
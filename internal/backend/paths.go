@@ -0,0 +1,33 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// CreateParents creates missing ancestors of the paths, the paths themselves are created by their users
+func CreateParents(conn Conn, acl []zk.ACL, paths ...string) error {
+	created := map[string]struct{}{}
+	for _, pth := range paths {
+		dir := path.Dir(pth)
+		if dir == "/" {
+			continue
+		}
+		parts := strings.Split(dir[1:], "/")
+		for i := range parts {
+			node := "/" + strings.Join(parts[:i+1], "/")
+			if _, ok := created[node]; ok {
+				continue
+			}
+			if _, err := conn.Create(node, []byte{}, 0, acl); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+				return fmt.Errorf("create %s: %w", node, err)
+			}
+			created[node] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var (
+	_ Connector = &SharedConnector{}
+	_ Conn      = &sharedConn{}
+)
+
+// SharedConnector lets many automatons use one zookeeper session. Connect returns the current session
+// while it's alive, so automatons reconnecting after an error converge on the same new session.
+// Session is closed when the last automaton holding it closes its connection.
+type SharedConnector struct {
+	connector Connector
+
+	mu  sync.Mutex // serializes connects, so concurrent reconnects open one session
+	cur atomic.Pointer[sharedSession]
+}
+
+type sharedSession struct {
+	conn Conn
+	refs int
+}
+
+func NewSharedConnector(connector Connector) *SharedConnector {
+	return &SharedConnector{connector: connector}
+}
+
+func (c *SharedConnector) Connect(ctx context.Context) (Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cur := c.cur.Load(); cur != nil && cur.conn.State() == zk.StateHasSession {
+		cur.refs++
+		return &sharedConn{Conn: cur.conn, connector: c, session: cur}, nil
+	}
+	// others keep using broken session until they reconnect too, it's closed when the last of them does
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cur := &sharedSession{conn: conn, refs: 1}
+	c.cur.Store(cur)
+	return &sharedConn{Conn: conn, connector: c, session: cur}, nil
+}
+
+// Current doesn't lock, it's called from log handler while Connect may be in progress
+func (c *SharedConnector) Current() Conn {
+	if cur := c.cur.Load(); cur != nil {
+		return cur.conn
+	}
+	return nil
+}
+
+func (c *SharedConnector) release(s *sharedSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.refs--
+	if s.refs > 0 {
+		return
+	}
+	s.conn.Close()
+	c.cur.CompareAndSwap(s, nil)
+}
+
+// sharedConn is a handle of shared session, closing it releases the session
+type sharedConn struct {
+	Conn
+	connector *SharedConnector
+	session   *sharedSession
+	closeOnce sync.Once
+}
+
+func (c *sharedConn) Close() {
+	c.closeOnce.Do(func() { c.connector.release(c.session) })
+}
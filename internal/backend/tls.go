@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/go-zookeeper/zk"
+)
+
+// dialer returns how connections to zookeeper servers are opened: plain tcp or tls.
+// Tls files are read on every dial, so rotated certificates are used by the next connection without restart.
+func dialer(opts cmdargs.RunArgs) zk.Dialer {
+	if !opts.ZkTLS {
+		return net.DialTimeout
+	}
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		cfg, err := tlsConfig(opts, address)
+		if err != nil {
+			return nil, err
+		}
+		return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, cfg)
+	}
+}
+
+// hostProvider passes server addresses to the dialer as they are given. Default zk provider resolves
+// host names to ip addresses, then server certificate would be verified against the ip instead of the name.
+type hostProvider struct {
+	mu      sync.Mutex
+	servers []string
+	curr    int
+	last    int
+}
+
+func (hp *hostProvider) Init(servers []string) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if len(servers) == 0 {
+		return fmt.Errorf("no zookeeper servers")
+	}
+	hp.servers = servers
+	hp.curr, hp.last = -1, -1
+	return nil
+}
+
+func (hp *hostProvider) Len() int {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	return len(hp.servers)
+}
+
+// Next returns the next server, retryStart is true once all servers were tried since the last connection
+func (hp *hostProvider) Next() (server string, retryStart bool) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	hp.curr = (hp.curr + 1) % len(hp.servers)
+	retryStart = hp.curr == hp.last
+	if hp.last == -1 {
+		hp.last = 0
+	}
+	return hp.servers[hp.curr], retryStart
+}
+
+func (hp *hostProvider) Connected() {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	hp.last = hp.curr
+}
+
+func tlsConfig(opts cmdargs.RunArgs, address string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: opts.ZkTLSServerName}
+	if cfg.ServerName == "" {
+		cfg.ServerName = address
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			cfg.ServerName = host
+		}
+	}
+	if opts.ZkTLSCA != "" {
+		pem, err := os.ReadFile(opts.ZkTLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("zk tls: read ca: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("zk tls: no certificates in ca file %s", opts.ZkTLSCA)
+		}
+	}
+	if opts.ZkTLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ZkTLSCert, opts.ZkTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("zk tls: load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
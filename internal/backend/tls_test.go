@@ -0,0 +1,268 @@
+package backend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns pem encoded certificate and key signed by ca
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// serveTLS starts tls terminating stand-in of zookeeper server which requires client certificate
+// signed by clientCA. It writes a byte to every client which passed handshake.
+func serveTLS(t *testing.T, serverCA, clientCA *testCA, name string) string {
+	t.Helper()
+	address, _ := serveTLSNames(t, serverCA, clientCA, name)
+	return address
+}
+
+// serveTLSNames starts the stand-in as serveTLS does and also reports server names sent by clients which passed handshake
+func serveTLSNames(t *testing.T, serverCA, clientCA *testCA, name string) (string, <-chan string) {
+	t.Helper()
+	names := make(chan string, 100)
+	certPEM, keyPEM := serverCA.issue(t, name, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err == nil {
+					select {
+					case names <- tlsConn.ConnectionState().ServerName:
+					default:
+					}
+					_, _ = conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), names
+}
+
+// tlsFiles writes ca and client certificate to files and returns options pointing to them
+func tlsFiles(t *testing.T, dir string, ca *testCA, certPEM, keyPEM []byte) cmdargs.RunArgs {
+	t.Helper()
+	opts := cmdargs.RunArgs{
+		ZkTLS:     true,
+		ZkTLSCA:   filepath.Join(dir, "ca.pem"),
+		ZkTLSCert: filepath.Join(dir, "client.pem"),
+		ZkTLSKey:  filepath.Join(dir, "client-key.pem"),
+	}
+	for pth, data := range map[string][]byte{opts.ZkTLSCA: ca.pem, opts.ZkTLSCert: certPEM, opts.ZkTLSKey: keyPEM} {
+		if err := os.WriteFile(pth, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return opts
+}
+
+// exchange dials address and reads a byte, with tls 1.3 client learns that server rejected its certificate only on read
+func exchange(opts cmdargs.RunArgs, address string) error {
+	conn, err := dialer(opts)("tcp", address, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+	_, err = conn.Read(make([]byte, 1))
+	return err
+}
+
+func localhost(address string) string {
+	_, port, _ := net.SplitHostPort(address)
+	return net.JoinHostPort("localhost", port)
+}
+
+func TestTLSHandshake(t *testing.T) {
+	serverCA, clientCA := newTestCA(t, "server ca"), newTestCA(t, "client ca")
+	address := serveTLS(t, serverCA, clientCA, "localhost")
+	certPEM, keyPEM := clientCA.issue(t, "node-1", x509.ExtKeyUsageClientAuth)
+	opts := tlsFiles(t, t.TempDir(), serverCA, certPEM, keyPEM)
+
+	// server name defaults to host of the address
+	if err := exchange(opts, localhost(address)); err != nil {
+		t.Fatalf("exchange with server name from address: %v", err)
+	}
+	opts.ZkTLSServerName = "localhost"
+	if err := exchange(opts, address); err != nil {
+		t.Fatalf("exchange with explicit server name: %v", err)
+	}
+}
+
+func TestTLSServerNameMismatch(t *testing.T) {
+	serverCA, clientCA := newTestCA(t, "server ca"), newTestCA(t, "client ca")
+	address := serveTLS(t, serverCA, clientCA, "localhost")
+	certPEM, keyPEM := clientCA.issue(t, "node-1", x509.ExtKeyUsageClientAuth)
+	opts := tlsFiles(t, t.TempDir(), serverCA, certPEM, keyPEM)
+	opts.ZkTLSServerName = "zk.example.com"
+
+	err := exchange(opts, address)
+	var hostErr x509.HostnameError
+	if !errors.As(err, &hostErr) {
+		t.Fatalf("exchange error = %v, want hostname mismatch", err)
+	}
+}
+
+func TestTLSMissingClientCertificate(t *testing.T) {
+	serverCA, clientCA := newTestCA(t, "server ca"), newTestCA(t, "client ca")
+	address := serveTLS(t, serverCA, clientCA, "localhost")
+	opts := tlsFiles(t, t.TempDir(), serverCA, nil, nil)
+	opts.ZkTLSCert, opts.ZkTLSKey = "", ""
+
+	if err := exchange(opts, localhost(address)); err == nil {
+		t.Fatal("server accepted client without certificate")
+	}
+}
+
+func TestTLSFilesAreReadOnEveryDial(t *testing.T) {
+	serverCA, clientCA := newTestCA(t, "server ca"), newTestCA(t, "client ca")
+	address := localhost(serveTLS(t, serverCA, clientCA, "localhost"))
+	dir := t.TempDir()
+
+	// neither ca trusts the server nor client certificate is trusted by it
+	otherCA := newTestCA(t, "other ca")
+	certPEM, keyPEM := otherCA.issue(t, "node-1", x509.ExtKeyUsageClientAuth)
+	opts := tlsFiles(t, dir, otherCA, certPEM, keyPEM)
+	var unknownAuthority x509.UnknownAuthorityError
+	if err := exchange(opts, address); !errors.As(err, &unknownAuthority) {
+		t.Fatalf("exchange error = %v, want unknown authority of server", err)
+	}
+
+	// rotated ca is picked up, server still rejects client certificate
+	tlsFiles(t, dir, serverCA, certPEM, keyPEM)
+	if err := exchange(opts, address); err == nil || errors.As(err, &unknownAuthority) {
+		t.Fatalf("exchange error = %v, want rejected client certificate", err)
+	}
+
+	// rotated client certificate is picked up
+	certPEM, keyPEM = clientCA.issue(t, "node-1", x509.ExtKeyUsageClientAuth)
+	tlsFiles(t, dir, serverCA, certPEM, keyPEM)
+	if err := exchange(opts, address); err != nil {
+		t.Fatalf("exchange after rotation: %v", err)
+	}
+}
+
+func TestTLSMissingCA(t *testing.T) {
+	opts := cmdargs.RunArgs{ZkTLS: true, ZkTLSCA: filepath.Join(t.TempDir(), "absent.pem")}
+	if _, err := dialer(opts)("tcp", "127.0.0.1:1", time.Second); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("dial error = %v, want missing ca file", err)
+	}
+}
+
+func TestTLSConnectByHostName(t *testing.T) {
+	serverCA, clientCA := newTestCA(t, "server ca"), newTestCA(t, "client ca")
+	// server certificate is issued for the name only, it isn't valid for 127.0.0.1 the name resolves to
+	address, names := serveTLSNames(t, serverCA, clientCA, "localhost")
+	certPEM, keyPEM := clientCA.issue(t, "node-1", x509.ExtKeyUsageClientAuth)
+	opts := tlsFiles(t, t.TempDir(), serverCA, certPEM, keyPEM)
+	opts.ZookeeperServers = []string{localhost(address)}
+	opts.SessionTimeout = 500 * time.Millisecond
+
+	// stand-in isn't zookeeper, so session is never established, but the handshake must pass
+	connector := NewZkConnector(slog.New(slog.NewTextHandler(io.Discard, nil)), cmdargs.NewLive(opts), nil)
+	if _, err := connector.Connect(context.Background()); err == nil {
+		t.Fatal("session established with tls stand-in")
+	}
+	select {
+	case name := <-names:
+		if name != "localhost" {
+			t.Fatalf("server name = %q, want localhost", name)
+		}
+	default:
+		t.Fatal("no handshake passed, server certificate is verified against resolved address")
+	}
+}
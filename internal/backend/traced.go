@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/tracing"
+	"github.com/go-zookeeper/zk"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ Connector = &TracedConnector{}
+	_ Conn      = &tracedConn{}
+)
+
+// TracedConnector records a span for connect and for every operation of connections it opens.
+// Operations are children of the span held by scope, connect is a child of its context.
+type TracedConnector struct {
+	connector Connector
+	scope     *tracing.Scope
+}
+
+func NewTracedConnector(connector Connector, scope *tracing.Scope) *TracedConnector {
+	return &TracedConnector{
+		connector: connector,
+		scope:     scope,
+	}
+}
+
+func (c *TracedConnector) Connect(ctx context.Context) (conn Conn, err error) {
+	ctx, end := startSpan(ctx, "connect", "")
+	defer end(&err)
+	if conn, err = c.connector.Connect(ctx); err != nil {
+		return nil, err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("zk.session_id", fmt.Sprintf("0x%x", conn.SessionID())))
+	return &tracedConn{conn: conn, scope: c.scope}, nil
+}
+
+func (c *TracedConnector) Current() Conn {
+	if conn := c.connector.Current(); conn != nil {
+		return &tracedConn{conn: conn, scope: c.scope}
+	}
+	return nil
+}
+
+// startSpan starts span of zookeeper operation, returned func has to be deferred with pointer to operation error
+func startSpan(ctx context.Context, op, path string) (context.Context, func(*error)) {
+	attrs := []attribute.KeyValue{attribute.String("zk.op", op)}
+	if path != "" {
+		attrs = append(attrs, attribute.String("zk.path", path))
+	}
+	ctx, span := tracing.Tracer(ctx).Start(ctx, "zk."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, ErrorClass(*err))
+		}
+		span.End()
+	}
+}
+
+type tracedConn struct {
+	conn  Conn
+	scope *tracing.Scope
+}
+
+func (c *tracedConn) start(op, path string) func(*error) {
+	_, end := startSpan(c.scope.Context(), op, path)
+	return end
+}
+
+func (c *tracedConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (res string, err error) {
+	defer c.start("create", path)(&err)
+	return c.conn.Create(path, data, flags, acl)
+}
+
+func (c *tracedConn) Delete(path string, version int32) (err error) {
+	defer c.start("delete", path)(&err)
+	return c.conn.Delete(path, version)
+}
+
+func (c *tracedConn) Children(path string) (res []string, stat *zk.Stat, err error) {
+	defer c.start("children", path)(&err)
+	return c.conn.Children(path)
+}
+
+func (c *tracedConn) Get(path string) (res []byte, stat *zk.Stat, err error) {
+	defer c.start("get", path)(&err)
+	return c.conn.Get(path)
+}
+
+func (c *tracedConn) GetACL(path string) (acl []zk.ACL, stat *zk.Stat, err error) {
+	defer c.start("get_acl", path)(&err)
+	return c.conn.GetACL(path)
+}
+
+func (c *tracedConn) Set(path string, data []byte, version int32) (stat *zk.Stat, err error) {
+	defer c.start("set", path)(&err)
+	return c.conn.Set(path, data, version)
+}
+
+func (c *tracedConn) Exists(path string) (res bool, stat *zk.Stat, err error) {
+	defer c.start("exists", path)(&err)
+	return c.conn.Exists(path)
+}
+
+func (c *tracedConn) ExistsW(path string) (res bool, stat *zk.Stat, ch <-chan zk.Event, err error) {
+	defer c.start("exists_watch", path)(&err)
+	return c.conn.ExistsW(path)
+}
+
+func (c *tracedConn) ChildrenW(path string) (res []string, stat *zk.Stat, ch <-chan zk.Event, err error) {
+	defer c.start("children_watch", path)(&err)
+	return c.conn.ChildrenW(path)
+}
+
+func (c *tracedConn) SessionID() int64 {
+	return c.conn.SessionID()
+}
+
+func (c *tracedConn) State() zk.State {
+	return c.conn.State()
+}
+
+func (c *tracedConn) SessionTimeout() time.Duration {
+	return c.conn.SessionTimeout()
+}
+
+func (c *tracedConn) Close() {
+	c.conn.Close()
+}
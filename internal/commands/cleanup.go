@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/cleanup"
+	"github.com/spf13/cobra"
+)
+
+func InitCleanupCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var dryRun, yes, force, all bool
+	cmd := &cobra.Command{
+		Use:     "cleanup",
+		Aliases: []string{"reset"},
+		Short:   "Removes stale election and leader dir nodes",
+		Long: `This command deletes persistent election node, foreign and out of capacity nodes in leader dir
+		and leader files with children. With --all or called as reset it resets the election: deletes election node and all leader files.
+		While election node belongs to a live session, it and leader files are deleted only with --force`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			args, err := loadZkArgs(cmd.Flags(), &cmdArgs)
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+
+			conn, err := backend.NewZkConnector(cliLogger(), cmdargs.NewLive(args), nil).Connect(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("connect to zookeeper: %w", err)
+			}
+			defer conn.Close()
+
+			all = all || cmd.CalledAs() == "reset"
+			targets, err := cleanup.Plan(conn, args, all)
+			if err != nil {
+				return fmt.Errorf("plan cleanup: %w", err)
+			}
+			out := cmd.OutOrStdout()
+			if len(targets) == 0 {
+				fmt.Fprintln(out, "Nothing to clean up")
+				return nil
+			}
+			if err := printTargets(out, targets); err != nil {
+				return err
+			}
+			for _, t := range targets {
+				if t.Live && !force {
+					return fmt.Errorf("refusing to delete %s, use --force if its owner is really gone: %w", t.Path, cleanup.ErrLiveSession)
+				}
+			}
+			if dryRun {
+				fmt.Fprintln(out, "Dry run, nothing deleted")
+				return nil
+			}
+			if !yes {
+				ok, err := confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d nodes with their children?", len(targets)))
+				if err != nil {
+					return fmt.Errorf("read confirmation: %w", err)
+				}
+				if !ok {
+					fmt.Fprintln(out, "Aborted")
+					return nil
+				}
+			}
+
+			if err := cleanup.Apply(conn, targets, force); err != nil {
+				return fmt.Errorf("clean up: %w", err)
+			}
+			fmt.Fprintf(out, "Deleted %d nodes\n", len(targets))
+			return nil
+		},
+	}
+
+	bindZkFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print nodes that would be deleted.")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Don't ask for confirmation.")
+	cmd.Flags().BoolVar(&force, "force", false, "Allow deleting election node and leader files of a live leader.")
+	cmd.Flags().BoolVar(&all, "all", false, "Delete election node and all leader files, not only stale ones.")
+
+	return cmd, nil
+}
+
+func printTargets(w io.Writer, targets []cleanup.Target) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tREASON")
+	for _, t := range targets {
+		fmt.Fprintf(tw, "%s\t%s\n", t.Path, t.Reason)
+	}
+	return tw.Flush()
+}
+
+// confirm asks question and reports whether user answered yes, no answer means no
+func confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
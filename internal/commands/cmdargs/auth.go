@@ -0,0 +1,88 @@
+package cmdargs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ACL modes of created nodes: open to everyone, writable by creator and readable by everyone, or creator only
+const (
+	ACLOpen        = "open"
+	ACLCreatorRead = "creator-read"
+	ACLCreator     = "creator"
+)
+
+// redacted replaces secrets in printed args
+const redacted = "******"
+
+// ZkCredentials returns digest credentials user:password from zk-auth or zk-auth-file, empty if auth is disabled.
+// The file is read on every call, so rotated credentials are used by the next connection.
+func (a RunArgs) ZkCredentials() (string, error) {
+	if a.ZkAuthFile == "" {
+		return a.ZkAuth, nil
+	}
+	data, err := os.ReadFile(a.ZkAuthFile)
+	if err != nil {
+		return "", fmt.Errorf("read zk auth file: %w", err)
+	}
+	creds := strings.TrimSpace(string(data))
+	if err := validateCredentials(creds); err != nil {
+		return "", fmt.Errorf("zk auth file %s: %w", a.ZkAuthFile, err)
+	}
+	return creds, nil
+}
+
+// AdminControlToken returns bearer token of admin control endpoints from admin-control-token-file,
+// empty if they are disabled. The file is read on every call, so token can be rotated without restart.
+func (a RunArgs) AdminControlToken() (string, error) {
+	if a.AdminControlTokenFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(a.AdminControlTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read admin control token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("admin control token file %s is empty", a.AdminControlTokenFile)
+	}
+	return token, nil
+}
+
+func validateCredentials(creds string) error {
+	user, _, ok := strings.Cut(creds, ":")
+	if !ok || user == "" {
+		return errors.New("credentials must look like user:password")
+	}
+	return nil
+}
+
+func (a RunArgs) securityErrors() []error {
+	var errs []error
+	if a.ZkAuth != "" && a.ZkAuthFile != "" {
+		errs = append(errs, errors.New("zk-auth and zk-auth-file must not be set together"))
+	}
+	if a.ZkAuth != "" {
+		if err := validateCredentials(a.ZkAuth); err != nil {
+			errs = append(errs, fmt.Errorf("zk-auth: %w", err))
+		}
+	}
+	if (a.ZkTLSCert == "") != (a.ZkTLSKey == "") {
+		errs = append(errs, errors.New("zk-tls-cert and zk-tls-key must be set together"))
+	}
+	if !a.ZkTLS && (a.ZkTLSCA != "" || a.ZkTLSCert != "" || a.ZkTLSServerName != "") {
+		errs = append(errs, errors.New("zk-tls-ca, zk-tls-cert and zk-tls-server-name require zk-tls"))
+	}
+	switch a.ZkACL {
+	case ACLOpen:
+	case ACLCreatorRead, ACLCreator:
+		if a.ZkAuth == "" && a.ZkAuthFile == "" {
+			errs = append(errs, fmt.Errorf("zk-acl: %s requires zk-auth or zk-auth-file", a.ZkACL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("zk-acl: must be %s, %s or %s, got %q", ACLOpen, ACLCreatorRead, ACLCreator, a.ZkACL))
+	}
+	return errs
+}
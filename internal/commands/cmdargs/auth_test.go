@@ -0,0 +1,42 @@
+package cmdargs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdminControlToken(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	a := validArgs()
+
+	if token, err := a.AdminControlToken(); token != "" || err != nil {
+		t.Fatalf("token without file = %q, %v, want disabled", token, err)
+	}
+
+	a.AdminControlTokenFile = file
+	if err := a.Validate(); err == nil || !strings.HasPrefix(err.Error(), "admin-control-token-file: read admin control token file") {
+		t.Fatalf("missing file: %v", err)
+	}
+	if err := os.WriteFile(file, []byte(" \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Validate(); err == nil || !strings.HasSuffix(err.Error(), "is empty") {
+		t.Fatalf("empty file: %v", err)
+	}
+
+	// the file is reread, so rotated token is used by the next request
+	for _, want := range []string{"first", "second"} {
+		if err := os.WriteFile(file, []byte(want+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if token, err := a.AdminControlToken(); token != want || err != nil {
+			t.Fatalf("token = %q, %v, want %q", token, err, want)
+		}
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package cmdargs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrUnsafeChange is returned when reloaded args change options that can't be applied to running states
+var ErrUnsafeChange = errors.New("options can't be changed without restart")
+
+// tunableFields are RunArgs fields which states reread while running
+var tunableFields = map[string]struct{}{
+	"LeaderTimeout":             {},
+	"AttempterTimeout":          {},
+	"StorageCapacity":           {},
+	"FailoverQuickRetryTimeout": {},
+	"FailoverSlowRetryStep":     {},
+	"FailoverMaxStateDuration":  {},
+	"Readiness":                 {},
+	"StuckStateTimeout":         {},
+	"LogLevel":                  {},
+	"HeartbeatInterval":         {},
+	"Priority":                  {},
+	"HandoverAfter":             {},
+	"MinLeaderTenure":           {},
+	"DemotionCooldown":          {},
+	"FlapThreshold":             {},
+	"FlapWindow":                {},
+	"FlapBackoff":               {},
+}
+
+// Live holds run args which can be updated while automaton is running
+type Live struct {
+	mu      sync.RWMutex
+	args    RunArgs
+	changed chan struct{}
+}
+
+func NewLive(args RunArgs) *Live {
+	return &Live{
+		args:    args,
+		changed: make(chan struct{}),
+	}
+}
+
+func (l *Live) Get() RunArgs {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.args
+}
+
+// Changed returns channel which is closed on the next applied update
+func (l *Live) Changed() <-chan struct{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.changed
+}
+
+// Update applies new args if they differ from current ones only in tunable fields.
+// It returns names of changed fields, nothing is applied if any of them isn't tunable.
+func (l *Live) Update(args RunArgs) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	changed := diffFields(l.args, args)
+	var unsafe []string
+	for _, field := range changed {
+		if _, ok := tunableFields[field]; !ok {
+			unsafe = append(unsafe, field)
+		}
+	}
+	if len(unsafe) != 0 {
+		return changed, fmt.Errorf("%w: %s", ErrUnsafeChange, strings.Join(unsafe, ", "))
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	l.args = args
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return changed, nil
+}
+
+func diffFields(a, b RunArgs) []string {
+	var res []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			res = append(res, va.Type().Field(i).Name)
+		}
+	}
+	return res
+}
@@ -0,0 +1,14 @@
+package cmdargs
+
+// Namespaced returns args with zookeeper paths prefixed with namespace, so teams sharing ensemble don't collide.
+// Paths of loaded args are set without namespace, they are made absolute paths in zookeeper by this copy.
+// Invalid namespace is left to validation, paths aren't prefixed with it.
+func (a RunArgs) Namespaced() RunArgs {
+	if a.Namespace == "" || validateZkPath(a.Namespace) != nil {
+		return a
+	}
+	for _, pth := range []*string{&a.ElectionFileDir, &a.LeaderFileDir, &a.MembersDir, &a.PartitionsDir} {
+		*pth = a.Namespace + *pth
+	}
+	return a
+}
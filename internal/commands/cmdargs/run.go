@@ -1,16 +1,86 @@
 package cmdargs
 
-import "time"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
 
 type RunArgs struct {
+	NodeID                    string
 	ZookeeperServers          []string
+	Namespace                 string // prefix of all paths, empty means root
+	ZkAuth                    string // digest credentials user:password, it's redacted in Values
+	ZkAuthFile                string
+	ZkACL                     string
+	ZkTLS                     bool
+	ZkTLSCA                   string // empty means system roots
+	ZkTLSCert                 string
+	ZkTLSKey                  string
+	ZkTLSServerName           string // empty means host of the server address
+	SessionTimeout            time.Duration
 	LeaderTimeout             time.Duration
 	AttempterTimeout          time.Duration
-	MaxDeadLeaderTimeout      time.Duration
+	MaxDeadLeaderTimeout      time.Duration // 0 means negotiated session timeout
 	FailoverQuickRetryTimeout time.Duration
 	FailoverSlowRetryStep     time.Duration
 	FailoverMaxStateDuration  time.Duration
 	ElectionFileDir           string
 	LeaderFileDir             string
 	StorageCapacity           int
+	AdminAddr                 string
+	AdminTLSCert              string
+	AdminTLSKey               string
+	AdminControlTokenFile     string // empty disables control endpoints of admin api
+	Readiness                 string
+	StuckStateTimeout         time.Duration
+	LogLevel                  string
+	LogFormat                 string
+	LogFile                   string
+	LogMaxSizeMB              int
+	LogMaxBackups             int
+	JournalFile               string // empty means transitions aren't journaled
+	JournalMaxSizeMB          int
+	JournalMaxBackups         int
+	TraceExporter             string
+	TraceFile                 string
+	Partitions                int // 0 disables partition ownership
+	PartitionsDir             string
+	MembersDir                string
+	HeartbeatInterval         time.Duration
+	Priority                  int           // candidates of higher priority win
+	HandoverAfter             time.Duration // 0 means node doesn't ask leader of lower priority to hand over
+	Observer                  bool          // node follows the election but never contends
+	MinLeaderTenure           time.Duration // leader doesn't hand over earlier
+	DemotionCooldown          time.Duration // node doesn't contest after leadership loss
+	FlapThreshold             int           // 0 disables flap detection
+	FlapWindow                time.Duration
+	FlapBackoff               time.Duration
+}
+
+// Readiness modes: node is ready only as leader or as leader and attemper
+const (
+	ReadinessLeader    = "leader"
+	ReadinessCandidate = "candidate"
+)
+
+// Values returns args as field name to printable value, durations look like 300ms and lists are comma separated
+func (a RunArgs) Values() map[string]string {
+	res := map[string]string{}
+	v := reflect.ValueOf(a)
+	for i := 0; i < v.NumField(); i++ {
+		switch val := v.Field(i).Interface().(type) {
+		case []string:
+			res[v.Type().Field(i).Name] = strings.Join(val, ",")
+		case string:
+			if v.Type().Field(i).Name == "ZkAuth" && val != "" {
+				val = redacted
+			}
+			res[v.Type().Field(i).Name] = val
+		default:
+			res[v.Type().Field(i).Name] = fmt.Sprint(val)
+		}
+	}
+	return res
 }
@@ -0,0 +1,279 @@
+package cmdargs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Validate checks invariants between run arguments that flags alone can't express.
+// All violations are returned at once joined with errors.Join, so user can fix config in one go.
+func (a RunArgs) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(a.NodeID) == "" {
+		errs = append(errs, errors.New("node-id: must not be empty"))
+	}
+	if strings.Contains(a.NodeID, "/") {
+		errs = append(errs, fmt.Errorf("node-id: %q must not contain / as it names member node", a.NodeID))
+	}
+	errs = append(errs, a.zkErrors()...)
+
+	durations := []struct {
+		flag string
+		val  time.Duration
+	}{
+		{"leader-timeout", a.LeaderTimeout},
+		{"attempter-timeout", a.AttempterTimeout},
+		{"failover-quick-retry-timeout", a.FailoverQuickRetryTimeout},
+		{"failover-slow-retry-step", a.FailoverSlowRetryStep},
+		{"failover-max-duration", a.FailoverMaxStateDuration},
+		{"stuck-state-timeout", a.StuckStateTimeout},
+		{"heartbeat-interval", a.HeartbeatInterval},
+	}
+	for _, d := range durations {
+		if d.val <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %s", d.flag, d.val))
+		}
+	}
+
+	for _, d := range []struct {
+		flag string
+		val  time.Duration
+	}{
+		{"handover-after", a.HandoverAfter},
+		{"min-leader-tenure", a.MinLeaderTenure},
+		{"demotion-cooldown", a.DemotionCooldown},
+	} {
+		if d.val < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %s", d.flag, d.val))
+		}
+	}
+	if a.FlapThreshold < 0 {
+		errs = append(errs, fmt.Errorf("flap-threshold: must not be negative, got %d", a.FlapThreshold))
+	}
+	if a.FlapThreshold > 0 && (a.FlapWindow <= 0 || a.FlapBackoff <= 0) {
+		errs = append(errs, fmt.Errorf("flap-window (%s) and flap-backoff (%s) must be positive when flap-threshold is set", a.FlapWindow, a.FlapBackoff))
+	}
+	if a.MaxDeadLeaderTimeout < 0 {
+		errs = append(errs, fmt.Errorf("dead-leader-timeout: must not be negative, got %s", a.MaxDeadLeaderTimeout))
+	}
+
+	// leader has to rewrite its files faster than zookeeper decides that it is dead
+	if a.LeaderTimeout >= a.SessionTimeout {
+		errs = append(errs, fmt.Errorf("leader-timeout (%s) must be less than session-timeout (%s)", a.LeaderTimeout, a.SessionTimeout))
+	}
+	// dead leader timeout is known only after session negotiation if not set, so requested session timeout is checked instead
+	deadLeader, deadLeaderFlag := a.MaxDeadLeaderTimeout, "dead-leader-timeout"
+	if deadLeader == 0 {
+		deadLeader, deadLeaderFlag = a.SessionTimeout, "session-timeout"
+	}
+	// failover quick retries last for dead-leader-timeout minus one quick retry,
+	// negative timeout is already reported above
+	if deadLeader >= 0 && a.FailoverQuickRetryTimeout >= deadLeader {
+		errs = append(errs, fmt.Errorf("failover-quick-retry-timeout (%s) must be less than %s (%s)", a.FailoverQuickRetryTimeout, deadLeaderFlag, deadLeader))
+	}
+	if deadLeader >= 0 && a.FailoverMaxStateDuration <= deadLeader {
+		errs = append(errs, fmt.Errorf("failover-max-duration (%s) must be greater than %s (%s)", a.FailoverMaxStateDuration, deadLeaderFlag, deadLeader))
+	}
+
+	// failover stops by itself after its max duration, so being there longer means automaton hangs
+	if a.StuckStateTimeout <= a.FailoverMaxStateDuration {
+		errs = append(errs, fmt.Errorf("stuck-state-timeout (%s) must be greater than failover-max-duration (%s)", a.StuckStateTimeout, a.FailoverMaxStateDuration))
+	}
+	// attemper and leader make progress every their timeout, so no progress for longer means they hang
+	if loop := max(a.LeaderTimeout, a.AttempterTimeout); a.StuckStateTimeout <= loop {
+		errs = append(errs, fmt.Errorf("stuck-state-timeout (%s) must be greater than leader-timeout and attempter-timeout (%s)", a.StuckStateTimeout, loop))
+	}
+
+	if a.StorageCapacity < 1 {
+		errs = append(errs, fmt.Errorf("storage-capacity: must be at least 1, got %d", a.StorageCapacity))
+	}
+
+	if a.AdminAddr == "" {
+		errs = append(errs, errors.New("admin-addr: address is required"))
+	}
+	if a.Readiness != ReadinessLeader && a.Readiness != ReadinessCandidate {
+		errs = append(errs, fmt.Errorf("readiness: must be %s or %s, got %q", ReadinessLeader, ReadinessCandidate, a.Readiness))
+	}
+	if (a.AdminTLSCert == "") != (a.AdminTLSKey == "") {
+		errs = append(errs, errors.New("admin-tls-cert and admin-tls-key must be set together"))
+	}
+	if _, err := a.AdminControlToken(); err != nil {
+		errs = append(errs, fmt.Errorf("admin-control-token-file: %w", err))
+	}
+
+	switch strings.ToLower(a.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log-level: must be debug, info, warn or error, got %q", a.LogLevel))
+	}
+	if a.LogFormat != "text" && a.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log-format: must be text or json, got %q", a.LogFormat))
+	}
+	if a.LogMaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("log-max-size: must not be negative, got %d", a.LogMaxSizeMB))
+	}
+	if a.LogMaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log-max-backups: must not be negative, got %d", a.LogMaxBackups))
+	}
+	if a.JournalMaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("journal-max-size: must not be negative, got %d", a.JournalMaxSizeMB))
+	}
+	if a.JournalMaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("journal-max-backups: must not be negative, got %d", a.JournalMaxBackups))
+	}
+	switch a.TraceExporter {
+	case "none", "stdout":
+	case "file":
+		if a.TraceFile == "" {
+			errs = append(errs, errors.New("trace-file: path is required by file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("trace-exporter: must be none, stdout or file, got %q", a.TraceExporter))
+	}
+	errs = append(errs, a.partitionErrors()...)
+
+	return errors.Join(errs...)
+}
+
+var electionNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateElections checks names of elections run by one process and that they don't share zookeeper paths
+func ValidateElections(elections map[string]RunArgs) error {
+	var errs []error
+	names := make([]string, 0, len(elections))
+	for name := range elections {
+		names = append(names, name)
+		if !electionNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("election name %q: only letters, digits, _ and - are allowed", name))
+		}
+	}
+	sort.Strings(names)
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			for _, x := range elections[a].paths() {
+				for _, y := range elections[b].paths() {
+					if isSubPath(x, y) || isSubPath(y, x) {
+						errs = append(errs, fmt.Errorf("elections %s and %s: paths %s and %s overlap", a, b, x, y))
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a RunArgs) partitionErrors() []error {
+	if a.Partitions < 0 {
+		return []error{fmt.Errorf("partitions: must not be negative, got %d", a.Partitions)}
+	}
+	if a.Partitions == 0 {
+		return nil
+	}
+	var errs []error
+	if err := validateZkPath(a.PartitionsDir); err != nil {
+		errs = append(errs, fmt.Errorf("partitions-dir: %w", err))
+	}
+	for _, pth := range []string{a.ElectionFileDir, a.LeaderFileDir, a.MembersDir} {
+		if isSubPath(a.PartitionsDir, pth) || isSubPath(pth, a.PartitionsDir) {
+			errs = append(errs, fmt.Errorf("partitions-dir (%s) and %s must not overlap", a.PartitionsDir, pth))
+		}
+	}
+	return errs
+}
+
+// paths returns zookeeper dirs used by the election
+func (a RunArgs) paths() []string {
+	res := []string{a.ElectionFileDir, a.LeaderFileDir, a.MembersDir}
+	if a.Partitions > 0 {
+		res = append(res, a.PartitionsDir)
+	}
+	return res
+}
+
+// ValidateZk checks only args needed to connect to zookeeper and find election paths,
+// it's used by commands that inspect the election instead of running it
+func (a RunArgs) ValidateZk() error {
+	return errors.Join(a.zkErrors()...)
+}
+
+func (a RunArgs) zkErrors() []error {
+	var errs []error
+
+	errs = append(errs, a.securityErrors()...)
+	if a.Namespace != "" {
+		if err := validateZkPath(a.Namespace); err != nil {
+			errs = append(errs, fmt.Errorf("namespace: %w", err))
+		}
+	}
+	if len(a.ZookeeperServers) == 0 {
+		errs = append(errs, errors.New("zk-servers: at least one server is required"))
+	}
+	for _, srv := range a.ZookeeperServers {
+		if strings.TrimSpace(srv) == "" {
+			errs = append(errs, errors.New("zk-servers: empty server address"))
+			break
+		}
+	}
+	if a.SessionTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("session-timeout: must be positive, got %s", a.SessionTimeout))
+	}
+	type dir struct {
+		flag string
+		pth  string
+	}
+	// only valid paths are checked for overlap, invalid ones are already reported
+	var dirs []dir
+	for _, d := range []dir{
+		{"election-file-dir", a.ElectionFileDir},
+		{"leader-file-dir", a.LeaderFileDir},
+		{"members-dir", a.MembersDir},
+	} {
+		if err := validateZkPath(d.pth); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.flag, err))
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	for i, x := range dirs {
+		for _, y := range dirs[i+1:] {
+			if isSubPath(x.pth, y.pth) || isSubPath(y.pth, x.pth) {
+				errs = append(errs, fmt.Errorf("%s (%s) and %s (%s) must not overlap", x.flag, x.pth, y.flag, y.pth))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateZkPath(pth string) error {
+	switch {
+	case pth == "":
+		return errors.New("path is empty")
+	case pth[0] != '/':
+		return fmt.Errorf("path %q must be absolute", pth)
+	case pth == "/":
+		return errors.New("path must not be the root node")
+	case strings.HasSuffix(pth, "/"):
+		return fmt.Errorf("path %q must not end with /", pth)
+	}
+	for _, part := range strings.Split(pth[1:], "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("path %q has empty or relative element", pth)
+		}
+	}
+	return nil
+}
+
+// isSubPath reports whether pth is equal to parent or lies under it
+func isSubPath(pth, parent string) bool {
+	if pth == "" || parent == "" {
+		return false
+	}
+	return pth == parent || strings.HasPrefix(pth, parent+"/")
+}
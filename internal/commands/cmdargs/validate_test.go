@@ -0,0 +1,176 @@
+package cmdargs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validArgs mirrors flag defaults
+func validArgs() RunArgs {
+	return RunArgs{
+		NodeID:                    "node",
+		ZookeeperServers:          []string{"zoo1:2181"},
+		ZkACL:                     ACLOpen,
+		SessionTimeout:            4 * time.Second,
+		LeaderTimeout:             300 * time.Millisecond,
+		AttempterTimeout:          300 * time.Millisecond,
+		FailoverQuickRetryTimeout: 50 * time.Millisecond,
+		FailoverSlowRetryStep:     500 * time.Millisecond,
+		FailoverMaxStateDuration:  10 * time.Second,
+		ElectionFileDir:           "/election",
+		LeaderFileDir:             "/data",
+		StorageCapacity:           5,
+		AdminAddr:                 ":8080",
+		Readiness:                 ReadinessCandidate,
+		StuckStateTimeout:         30 * time.Second,
+		LogLevel:                  "info",
+		LogFormat:                 "text",
+		TraceExporter:             "none",
+		PartitionsDir:             "/partitions",
+		MembersDir:                "/members",
+		HeartbeatInterval:         5 * time.Second,
+		FlapWindow:                5 * time.Minute,
+		FlapBackoff:               time.Minute,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *RunArgs)
+		errs   []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(a *RunArgs) {},
+		},
+		{
+			name:   "negative quick retry",
+			modify: func(a *RunArgs) { a.FailoverQuickRetryTimeout = -time.Millisecond },
+			errs:   []string{"failover-quick-retry-timeout: must be positive, got -1ms"},
+		},
+		{
+			name:   "quick retry reaches session timeout",
+			modify: func(a *RunArgs) { a.FailoverQuickRetryTimeout = a.SessionTimeout },
+			errs:   []string{"failover-quick-retry-timeout (4s) must be less than session-timeout (4s)"},
+		},
+		{
+			name: "quick retry reaches dead leader timeout",
+			modify: func(a *RunArgs) {
+				a.MaxDeadLeaderTimeout = time.Second
+				a.FailoverQuickRetryTimeout = 2 * time.Second
+			},
+			errs: []string{"failover-quick-retry-timeout (2s) must be less than dead-leader-timeout (1s)"},
+		},
+		{
+			name:   "negative dead leader timeout",
+			modify: func(a *RunArgs) { a.MaxDeadLeaderTimeout = -time.Second },
+			errs:   []string{"dead-leader-timeout: must not be negative, got -1s"},
+		},
+		{
+			name:   "zero capacity",
+			modify: func(a *RunArgs) { a.StorageCapacity = 0 },
+			errs:   []string{"storage-capacity: must be at least 1, got 0"},
+		},
+		{
+			name:   "relative path",
+			modify: func(a *RunArgs) { a.LeaderFileDir = "data" },
+			errs:   []string{`leader-file-dir: path "data" must be absolute`},
+		},
+		{
+			name:   "trailing slash",
+			modify: func(a *RunArgs) { a.ElectionFileDir = "/election/" },
+			errs:   []string{`election-file-dir: path "/election/" must not end with /`},
+		},
+		{
+			name:   "parent element",
+			modify: func(a *RunArgs) { a.MembersDir = "/election/../members" },
+			errs:   []string{`members-dir: path "/election/../members" has empty or relative element`},
+		},
+		{
+			name:   "root node",
+			modify: func(a *RunArgs) { a.Namespace = "/" },
+			errs:   []string{"namespace: path must not be the root node"},
+		},
+		{
+			name:   "nested dirs",
+			modify: func(a *RunArgs) { a.LeaderFileDir = "/election/data" },
+			errs:   []string{"election-file-dir (/election) and leader-file-dir (/election/data) must not overlap"},
+		},
+		{
+			name:   "same dirs",
+			modify: func(a *RunArgs) { a.MembersDir = "/data" },
+			errs:   []string{"leader-file-dir (/data) and members-dir (/data) must not overlap"},
+		},
+		{
+			name:   "common prefix is not overlap",
+			modify: func(a *RunArgs) { a.LeaderFileDir = "/election-data" },
+		},
+		{
+			name: "partitions under members",
+			modify: func(a *RunArgs) {
+				a.Partitions = 4
+				a.PartitionsDir = "/members/partitions"
+			},
+			errs: []string{"partitions-dir (/members/partitions) and /members must not overlap"},
+		},
+		{
+			name: "joined errors",
+			modify: func(a *RunArgs) {
+				a.NodeID = ""
+				a.StorageCapacity = 0
+				a.LeaderFileDir = "data"
+				a.LogFormat = "xml"
+			},
+			errs: []string{
+				"node-id: must not be empty",
+				`leader-file-dir: path "data" must be absolute`,
+				"storage-capacity: must be at least 1, got 0",
+				`log-format: must be text or json, got "xml"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := validArgs()
+			tt.modify(&a)
+			err := a.Validate()
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q, got nil", tt.errs)
+			}
+			// joined errors are separated by new lines
+			got := strings.Split(err.Error(), "\n")
+			if len(got) != len(tt.errs) {
+				t.Fatalf("expected %d errors, got %d: %q", len(tt.errs), len(got), got)
+			}
+			for i, msg := range tt.errs {
+				if got[i] != msg {
+					t.Errorf("error %d: expected %q, got %q", i, msg, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateElectionsOverlap(t *testing.T) {
+	a, b := validArgs(), validArgs()
+	b.ElectionFileDir = "/b/election"
+	b.LeaderFileDir = "/b/data"
+	b.MembersDir = "/b/members"
+	if err := ValidateElections(map[string]RunArgs{"a": a, "b": b}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b.LeaderFileDir = "/data/b"
+	err := ValidateElections(map[string]RunArgs{"a": a, "b": b})
+	if err == nil || err.Error() != "elections a and b: paths /data and /data/b overlap" {
+		t.Fatalf("expected overlap error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func InitConfigCommand() (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspects the node configuration",
+	}
+
+	printCmd, err := initConfigPrintCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init config print command: %w", err)
+	}
+	cmd.AddCommand(printCmd)
+
+	return cmd, nil
+}
+
+type configEntry struct {
+	Name   string        `json:"name"`
+	Value  string        `json:"value"`
+	Source config.Source `json:"source"`
+}
+
+func initConfigPrintCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Prints effective configuration of the run command",
+		Long: `This command merges flags, ELECTION_* environment variables, config file and defaults
+		the same way run does and prints every option with the source its value came from`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			sources, err := config.Load(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+			validateErr := cmdArgs.Namespaced().Validate()
+
+			entries := make([]configEntry, 0, len(sources))
+			for _, name := range sources.Names() {
+				if name == "output" {
+					continue
+				}
+				value := config.Value(cmd.Flags().Lookup(name))
+				if _, secret := secretFlags[name]; secret && value != "" {
+					value = "******"
+				}
+				entries = append(entries, configEntry{Name: name, Value: value, Source: sources[name]})
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(entries); err != nil {
+					return fmt.Errorf("encode config: %w", err)
+				}
+			case "text":
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "OPTION\tVALUE\tSOURCE")
+				for _, e := range entries {
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Name, e.Value, e.Source)
+				}
+				if err := tw.Flush(); err != nil {
+					return fmt.Errorf("print config: %w", err)
+				}
+			default:
+				return fmt.Errorf("unknown output format %q", output)
+			}
+
+			if validateErr != nil {
+				return fmt.Errorf("invalid config: %w", validateErr)
+			}
+			return nil
+		},
+	}
+
+	bindRunFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Set the output format: text or json.")
+
+	return cmd, nil
+}
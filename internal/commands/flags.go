@@ -0,0 +1,271 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
+	"github.com/spf13/pflag"
+)
+
+// bindRunFlags registers flags of RunArgs, they are shared by every command that talks to the election
+func bindRunFlags(fs *pflag.FlagSet, cmdArgs *cmdargs.RunArgs) {
+	hostname, _ := os.Hostname()
+	fs.String(config.ConfigFlag, "", "Set the path to yaml, toml or json config file, its keys are the flag names.")
+	fs.StringVar(&(cmdArgs.NodeID), "node-id", hostname, "Set the node identity written to election node by the leader.")
+	fs.StringSliceVarP(&(cmdArgs.ZookeeperServers), "zk-servers", "s", []string{"zoo1:2181", "zoo2:2182", "zoo3:2183"}, "Set the zookeeper servers.")
+	fs.StringVar(&(cmdArgs.Namespace), "namespace", "", "Set the path all zookeeper paths are prefixed with, like chroot, so that teams can share ensemble.")
+	fs.StringVar(&(cmdArgs.ZkAuth), "zk-auth", "", "Set the zookeeper digest credentials user:password, prefer "+config.EnvName("zk-auth")+" env or --zk-auth-file.")
+	fs.StringVar(&(cmdArgs.ZkAuthFile), "zk-auth-file", "", "Set the file with zookeeper digest credentials user:password, it's reread on reconnect.")
+	fs.StringVar(&(cmdArgs.ZkACL), "zk-acl", cmdargs.ACLOpen, "Set the ACL of created nodes: open, creator-read - only creator writes, or creator - only creator has access.")
+	fs.BoolVar(&(cmdArgs.ZkTLS), "zk-tls", false, "Set to connect to zookeeper over tls.")
+	fs.StringVar(&(cmdArgs.ZkTLSCA), "zk-tls-ca", "", "Set the ca bundle to verify zookeeper servers, system roots are used if empty.")
+	fs.StringVar(&(cmdArgs.ZkTLSCert), "zk-tls-cert", "", "Set the client certificate file for zookeeper tls.")
+	fs.StringVar(&(cmdArgs.ZkTLSKey), "zk-tls-key", "", "Set the client key file for zookeeper tls.")
+	fs.StringVar(&(cmdArgs.ZkTLSServerName), "zk-tls-server-name", "", "Set the name zookeeper server certificates are verified for, host of the server address by default.")
+	fs.DurationVar(&(cmdArgs.SessionTimeout), "session-timeout", 4*time.Second, "Set the requested zookeeper session timeout, server may negotiate another one.")
+	fs.DurationVarP(&(cmdArgs.LeaderTimeout), "leader-timeout", "l", 300*time.Millisecond, "Set the leader file write timeout.")
+	fs.DurationVarP(&(cmdArgs.MaxDeadLeaderTimeout), "dead-leader-timeout", "t", 0, "Set the max timeout zookeper will wait for dead leader, by default negotiated session timeout is used.")
+	fs.DurationVarP(&(cmdArgs.FailoverQuickRetryTimeout), "failover-quick-retry-timeout", "q", 50*time.Millisecond, "Set retry frequency to reconnect to zookeper as dead leader.")
+	fs.DurationVarP(&(cmdArgs.FailoverSlowRetryStep), "failover-slow-retry-step", "r", 500*time.Millisecond, "Set step timeout of retrying to return to attemper state .")
+	fs.DurationVarP(&(cmdArgs.FailoverMaxStateDuration), "failover-max-duration", "w", 10*time.Second, "Set max failover duration as a state.")
+	fs.DurationVarP(&(cmdArgs.AttempterTimeout), "attempter-timeout", "a", 300*time.Millisecond, "Set the attempt to become leader timeout.")
+	fs.StringVarP(&(cmdArgs.ElectionFileDir), "election-file-dir", "f", "/election", "Set the path to write file to become leader.")
+	fs.StringVarP(&(cmdArgs.LeaderFileDir), "leader-file-dir", "d", "/data", "Set the path to write files as leader.")
+	fs.IntVarP(&(cmdArgs.StorageCapacity), "storage-capacity", "c", 5, "Set max amount of files in leader dir.")
+	fs.StringVar(&(cmdArgs.AdminAddr), "admin-addr", ":8080", "Set the address of admin http server with metrics.")
+	fs.StringVar(&(cmdArgs.AdminTLSCert), "admin-tls-cert", "", "Set the certificate file to serve admin http server over tls.")
+	fs.StringVar(&(cmdArgs.AdminTLSKey), "admin-tls-key", "", "Set the key file to serve admin http server over tls.")
+	fs.StringVar(&(cmdArgs.AdminControlTokenFile), "admin-control-token-file", "", "Set the file with bearer token required by resign, pause and resume admin api, they are disabled if empty. It's reread on every request.")
+	fs.StringVar(&(cmdArgs.Readiness), "readiness", cmdargs.ReadinessCandidate, "Set when /readyz succeeds: leader - only on leader, candidate - in attemper and leader states.")
+	fs.DurationVar(&(cmdArgs.StuckStateTimeout), "stuck-state-timeout", 30*time.Second, "Set how long node may stay out of attemper and leader states or make no progress in them before /healthz fails.")
+	fs.StringVar(&(cmdArgs.LogLevel), "log-level", "info", "Set the log level: debug, info, warn or error.")
+	fs.StringVar(&(cmdArgs.LogFormat), "log-format", "text", "Set the log format: text or json.")
+	fs.StringVar(&(cmdArgs.LogFile), "log-file", "", "Set the file to write logs to instead of stdout.")
+	fs.IntVar(&(cmdArgs.LogMaxSizeMB), "log-max-size", 100, "Set the log file size in megabytes to rotate it at, 0 disables rotation.")
+	fs.IntVar(&(cmdArgs.LogMaxBackups), "log-max-backups", 3, "Set how many rotated log files to keep.")
+	fs.StringVar(&(cmdArgs.JournalFile), "journal-file", "", "Set the file to append state transitions to, journal is disabled if empty.")
+	fs.IntVar(&(cmdArgs.JournalMaxSizeMB), "journal-max-size", 10, "Set the journal file size in megabytes to rotate it at, 0 disables rotation.")
+	fs.IntVar(&(cmdArgs.JournalMaxBackups), "journal-max-backups", 5, "Set how many rotated journal files to keep.")
+	fs.StringVar(&(cmdArgs.TraceExporter), "trace-exporter", "none", "Set where spans are exported: none, stdout or file.")
+	fs.StringVar(&(cmdArgs.TraceFile), "trace-file", "", "Set the file to append spans to as json lines, used by file exporter.")
+	fs.IntVar(&(cmdArgs.Partitions), "partitions", 0, "Set the amount of partitions distributed among contending nodes, 0 disables partitioning.")
+	fs.StringVar(&(cmdArgs.PartitionsDir), "partitions-dir", "/partitions", "Set the path to write partition ownership nodes.")
+	fs.StringVar(&(cmdArgs.MembersDir), "members-dir", "/members", "Set the path to register attemper and leader nodes as members.")
+	fs.IntVar(&(cmdArgs.Priority), "priority", 0, "Set the candidacy priority, nodes defer to eligible candidates of higher priority.")
+	fs.DurationVar(&(cmdArgs.HandoverAfter), "handover-after", 0, "Set how long node waits as candidate before asking leader of lower priority to hand over, 0 disables handover.")
+	fs.BoolVar(&(cmdArgs.Observer), "observer", false, "Set to follow the election and expose status and metrics without ever becoming leader.")
+	fs.DurationVar(&(cmdArgs.MinLeaderTenure), "min-leader-tenure", 0, "Set how long leader keeps leadership before it may hand it over or resign without force.")
+	fs.DurationVar(&(cmdArgs.DemotionCooldown), "demotion-cooldown", 0, "Set how long node doesn't contest after it lost leadership.")
+	fs.IntVar(&(cmdArgs.FlapThreshold), "flap-threshold", 0, "Set how many leadership losses within flap window make node back off, 0 disables flap detection.")
+	fs.DurationVar(&(cmdArgs.FlapWindow), "flap-window", 5*time.Minute, "Set the window leadership losses are counted in.")
+	fs.DurationVar(&(cmdArgs.FlapBackoff), "flap-backoff", time.Minute, "Set how long flapping node doesn't contest.")
+	fs.DurationVar(&(cmdArgs.HeartbeatInterval), "heartbeat-interval", 5*time.Second, "Set how often member node is rewritten with the current state.")
+}
+
+// secretFlags are printed redacted
+var secretFlags = map[string]struct{}{
+	"zk-auth": {},
+}
+
+// zkFlags are flags which commands inspecting the election use, other run flags are hidden there
+var zkFlags = map[string]struct{}{
+	config.ConfigFlag:    {},
+	"zk-servers":         {},
+	"namespace":          {},
+	"zk-auth":            {},
+	"zk-auth-file":       {},
+	"zk-tls":             {},
+	"zk-tls-ca":          {},
+	"zk-tls-cert":        {},
+	"zk-tls-key":         {},
+	"zk-tls-server-name": {},
+	"session-timeout":    {},
+	"leader-timeout":     {},
+	"election-file-dir":  {},
+	"leader-file-dir":    {},
+	"storage-capacity":   {},
+	"members-dir":        {},
+	electionFlag:         {},
+}
+
+// electionFlag selects named election from config file for commands inspecting the election
+const electionFlag = "election"
+
+// bindZkFlags registers run flags for commands that inspect the election. All run flags are bound,
+// so config file written for run is accepted, but only connection and path ones are shown in help.
+func bindZkFlags(fs *pflag.FlagSet, cmdArgs *cmdargs.RunArgs) {
+	bindRunFlags(fs, cmdArgs)
+	fs.String(electionFlag, "", "Set the named election from config file to use paths and timeouts of.")
+	hideFlagsExcept(fs, zkFlags)
+}
+
+// journalFlags are flags shown by the journal command
+var journalFlags = map[string]struct{}{
+	config.ConfigFlag:     {},
+	"journal-file":        {},
+	"journal-max-backups": {},
+}
+
+// bindJournalFlags registers run flags for commands reading transition journal
+func bindJournalFlags(fs *pflag.FlagSet, cmdArgs *cmdargs.RunArgs) {
+	bindRunFlags(fs, cmdArgs)
+	hideFlagsExcept(fs, journalFlags)
+}
+
+func hideFlagsExcept(fs *pflag.FlagSet, shown map[string]struct{}) {
+	fs.VisitAll(func(fl *pflag.Flag) {
+		if _, ok := shown[fl.Name]; !ok {
+			fl.Hidden = true
+		}
+	})
+}
+
+// loadZkArgs is loadRunArgs for commands bound with bindZkFlags
+func loadZkArgs(fs *pflag.FlagSet, cmdArgs *cmdargs.RunArgs) (cmdargs.RunArgs, error) {
+	if _, err := config.Load(fs); err != nil {
+		return cmdargs.RunArgs{}, err
+	}
+	if name, _ := fs.GetString(electionFlag); name != "" {
+		if err := applyElection(fs, name); err != nil {
+			return cmdargs.RunArgs{}, err
+		}
+	}
+	args := cmdArgs.Namespaced()
+	return args, args.ValidateZk()
+}
+
+// loadRunArgs applies env and config file on top of flags and validates the result.
+// Flags keep values as they were set, returned args have paths prefixed with namespace.
+func loadRunArgs(fs *pflag.FlagSet, cmdArgs *cmdargs.RunArgs) (cmdargs.RunArgs, config.Sources, error) {
+	sources, err := config.Load(fs)
+	if err != nil {
+		return cmdargs.RunArgs{}, nil, err
+	}
+	args := cmdArgs.Namespaced()
+	return args, sources, args.Validate()
+}
+
+// reloadRunArgs loads run args again keeping values passed on command line, it's used on config reload
+// and to load args of named election, whose options from config file are applied over other sources
+func reloadRunArgs(cmdFlags *pflag.FlagSet, election string) (cmdargs.RunArgs, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	fs := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	bindRunFlags(fs, &cmdArgs)
+
+	var err error
+	cmdFlags.Visit(func(fl *pflag.Flag) {
+		if fs.Lookup(fl.Name) != nil && err == nil {
+			err = fs.Set(fl.Name, config.Value(fl))
+		}
+	})
+	if err != nil {
+		return cmdArgs, fmt.Errorf("copy command line flags: %w", err)
+	}
+
+	if _, err := config.Load(fs); err != nil {
+		return cmdArgs, err
+	}
+	if election != "" {
+		if err := applyElection(fs, election); err != nil {
+			return cmdArgs, err
+		}
+	}
+	args := cmdArgs.Namespaced()
+	return args, args.Validate()
+}
+
+// electionFlags may be set per named election, other flags are process wide
+var electionFlags = map[string]struct{}{
+	"election-file-dir":            {},
+	"leader-file-dir":              {},
+	"leader-timeout":               {},
+	"attempter-timeout":            {},
+	"dead-leader-timeout":          {},
+	"failover-quick-retry-timeout": {},
+	"failover-slow-retry-step":     {},
+	"failover-max-duration":        {},
+	"storage-capacity":             {},
+	"readiness":                    {},
+	"stuck-state-timeout":          {},
+	"partitions":                   {},
+	"partitions-dir":               {},
+	"members-dir":                  {},
+	"priority":                     {},
+	"handover-after":               {},
+	"observer":                     {},
+	"min-leader-tenure":            {},
+	"demotion-cooldown":            {},
+	"flap-threshold":               {},
+	"flap-window":                  {},
+	"flap-backoff":                 {},
+}
+
+// electionNames returns names of elections listed in config file, nil if process runs the only unnamed one
+func electionNames(fs *pflag.FlagSet) ([]string, error) {
+	elections, err := config.Elections(fs)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(elections))
+	seen := map[string]bool{}
+	for _, e := range elections {
+		if seen[e.Name] {
+			return nil, fmt.Errorf("config file: election %q is listed twice", e.Name)
+		}
+		seen[e.Name] = true
+		names = append(names, e.Name)
+	}
+	return names, nil
+}
+
+// loadElectionArgs returns args of every named election from config file, or of the only unnamed one
+func loadElectionArgs(fs *pflag.FlagSet, cmdArgs cmdargs.RunArgs) (map[string]cmdargs.RunArgs, error) {
+	names, err := electionNames(fs)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return map[string]cmdargs.RunArgs{"": cmdArgs}, nil
+	}
+
+	res := make(map[string]cmdargs.RunArgs, len(names))
+	for _, name := range names {
+		if res[name], err = reloadRunArgs(fs, name); err != nil {
+			return nil, fmt.Errorf("election %s: %w", name, err)
+		}
+	}
+	return res, cmdargs.ValidateElections(res)
+}
+
+func sortedNames(elections map[string]cmdargs.RunArgs) []string {
+	names := make([]string, 0, len(elections))
+	for name := range elections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyElection applies options of named election from config file to flags
+func applyElection(fs *pflag.FlagSet, name string) error {
+	elections, err := config.Elections(fs)
+	if err != nil {
+		return err
+	}
+	for _, e := range elections {
+		if e.Name != name {
+			continue
+		}
+		for key := range e.Options {
+			if _, ok := electionFlags[key]; !ok && fs.Lookup(key) != nil {
+				return fmt.Errorf("election %s: option %q is process wide and can't be set per election", name, key)
+			}
+		}
+		return e.Apply(fs)
+	}
+	return fmt.Errorf("election %q is not listed in config file", name)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+func InitJournalCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var output, since, until string
+	filter := journal.Filter{}
+	cmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Prints state transitions journaled by this node",
+		Long: `This command reads the local transition journal written by run with --journal-file,
+		including rotated files, and prints transitions from the oldest to the newest`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if _, err := config.Load(cmd.Flags()); err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+			if cmdArgs.JournalFile == "" {
+				return errors.New("journal-file: path is required")
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q", output)
+			}
+			var err error
+			if filter.Since, err = parseTimeFlag(since, time.Now()); err != nil {
+				return fmt.Errorf("since: %w", err)
+			}
+			if filter.Until, err = parseTimeFlag(until, time.Now()); err != nil {
+				return fmt.Errorf("until: %w", err)
+			}
+
+			entries, err := journal.Read(cmdArgs.JournalFile, cmdArgs.JournalMaxBackups, filter)
+			if err != nil {
+				return fmt.Errorf("read journal: %w", err)
+			}
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				for _, e := range entries {
+					if err := enc.Encode(e); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			return printEntries(cmd.OutOrStdout(), entries)
+		},
+	}
+
+	bindJournalFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Set the output format: text or json lines.")
+	cmd.Flags().StringVar(&since, "since", "", "Print transitions after this time, RFC3339 or duration ago like 2h.")
+	cmd.Flags().StringVar(&until, "until", "", "Print transitions before this time, RFC3339 or duration ago like 2h.")
+	cmd.Flags().StringVar(&filter.State, "state", "", "Print only transitions from or to this state, like LeaderState.")
+	cmd.Flags().StringVar(&filter.Election, "election", "", "Print only transitions of this named election.")
+	cmd.Flags().IntVarP(&filter.Last, "last", "n", 0, "Print only this many latest transitions.")
+
+	return cmd, nil
+}
+
+// parseTimeFlag parses RFC3339 time or duration back from now, empty value is zero time
+func parseTimeFlag(val string, now time.Time) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(val); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+func printEntries(w io.Writer, entries []journal.Entry) error {
+	for _, e := range entries {
+		line := e.Time.Format(time.RFC3339Nano)
+		if e.Election != "" {
+			line += " [" + e.Election + "]"
+		}
+		line += fmt.Sprintf(" %s -> %s", e.From, e.To)
+		if e.Reason != "" {
+			line += " reason=" + e.Reason
+		}
+		if e.SessionID != 0 {
+			line += fmt.Sprintf(" session=0x%x", e.SessionID)
+		}
+		if e.Epoch != 0 {
+			line += fmt.Sprintf(" epoch=%d", e.Epoch)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
+	"github.com/spf13/cobra"
+)
+
+func InitMembersCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "members",
+		Short: "Lists nodes taking part in the election",
+		Long: `This command connects to zookeeper and prints attemper and leader nodes
+		registered in members dir with their state and age of the last heartbeat`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			args, err := loadZkArgs(cmd.Flags(), &cmdArgs)
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q", output)
+			}
+
+			conn, err := backend.NewZkConnector(cliLogger(), cmdargs.NewLive(args), nil).Connect(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("connect to zookeeper: %w", err)
+			}
+			defer conn.Close()
+
+			members, err := membership.List(conn, args.MembersDir)
+			if err != nil {
+				return fmt.Errorf("list members: %w", err)
+			}
+
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(members)
+			}
+			return printMembers(cmd.OutOrStdout(), members, args.HeartbeatInterval, time.Now())
+		},
+	}
+
+	bindZkFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Set the output format: text or json.")
+
+	return cmd, nil
+}
+
+func printMembers(w io.Writer, members []membership.Member, heartbeat time.Duration, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	leader := "none"
+	var healthy, stale int
+	fmt.Fprintln(tw, "NODE\tSTATE\tPRIORITY\tIN STATE\tHEARTBEAT\tSESSION\tADMIN")
+	for _, m := range members {
+		age := now.Sub(m.Heartbeat).Round(time.Second).String() + " ago"
+		switch {
+		case m.Stale(now, heartbeat):
+			age += " (stale)"
+			stale++
+		case m.State == membership.StateLeader:
+			leader = m.NodeID
+		case m.Eligible:
+			healthy++
+		}
+		state := m.State
+		switch {
+		case m.Handover:
+			state += " (handover)"
+		case !m.Eligible:
+			state += " (paused)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t0x%x\t%s\n", m.NodeID, state, m.Priority, now.Sub(m.Since).Round(time.Second), age, m.SessionID, m.AdminAddr)
+	}
+	fmt.Fprintf(tw, "\nLeader: %s, healthy standbys: %d, stale members: %d\n", leader, healthy, stale)
+	return tw.Flush()
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func InitRootCommand() (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   "election",
+		Short: "Leader election node on top of zookeeper",
+	}
+
+	runCmd, err := InitRunCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init run command: %w", err)
+	}
+	configCmd, err := InitConfigCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init config command: %w", err)
+	}
+	statusCmd, err := InitStatusCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init status command: %w", err)
+	}
+	cleanupCmd, err := InitCleanupCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init cleanup command: %w", err)
+	}
+	watchCmd, err := InitWatchCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init watch command: %w", err)
+	}
+	journalCmd, err := InitJournalCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init journal command: %w", err)
+	}
+	membersCmd, err := InitMembersCommand()
+	if err != nil {
+		return nil, fmt.Errorf("init members command: %w", err)
+	}
+	cmd.AddCommand(runCmd, configCmd, statusCmd, cleanupCmd, watchCmd, journalCmd, membersCmd)
+
+	return cmd, nil
+}
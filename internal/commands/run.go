@@ -8,31 +8,41 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
-	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/admin"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/logging"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/reload"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
 
-func InitRunCommand() (cobra.Command, error) {
+func InitRunCommand() (*cobra.Command, error) {
 	cmdArgs := cmdargs.RunArgs{}
-	cmd := cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Starts a leader election node",
 		Long: `This command starts the leader election node that connects to zookeeper
 		and starts to try to acquire leadership by creation of ephemeral node`,
 		RunE: func(cmd *cobra.Command, _ []string) (returnErr error) {
+			args, _, err := loadRunArgs(cmd.Flags(), &cmdArgs)
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+
 			dg := depgraph.New()
 			// zkConn, err := dg.GetZkConn() ??? не понимаю осмысленности запускать это тут
-			logger, err := dg.GetLogger()
+			logger, err := dg.GetLogger(args)
 			if err != nil {
 				return fmt.Errorf("get logger: %w", err)
 			}
-			logger.Info("args received", slog.String("servers", strings.Join(cmdArgs.ZookeeperServers, ", ")))
+			defer dg.Close()
+			logger.Info("args received", slog.String("servers", strings.Join(args.ZookeeperServers, ", ")))
 
 			ctx, cncl := context.WithCancelCause(cmd.Context())
 			defer func() {
@@ -56,42 +66,89 @@ func InitRunCommand() (cobra.Command, error) {
 				}
 			}()
 
-			metrics := metrics.InitPrometheus(ctx, logger, eg)
-
-			runner, err := dg.GetRunner(metrics)
+			electionArgs, err := loadElectionArgs(cmd.Flags(), args)
 			if err != nil {
-				return fmt.Errorf("get runner: %w", err)
+				return fmt.Errorf("load elections: %w", err)
 			}
 
-			initState, err := dg.GetInitState(ticker.GetTicker(), cmdArgs)
+			adminSrv := admin.New(logger, args.AdminAddr, args.AdminTLSCert, args.AdminTLSKey)
+			metrics := metrics.InitPrometheus(ctx, logger, adminSrv, args.Namespace)
+			adminSrv.Run(ctx, eg)
+
+			shared, err := dg.GetSharedConnector(cmdargs.NewLive(args), metrics)
 			if err != nil {
-				return fmt.Errorf("get init state: %w", err)
+				return fmt.Errorf("get shared connector: %w", err)
 			}
 
-			logger.Info("app started init state")
-			err = runner.Run(ctx, initState)
-			if err != nil {
-				return fmt.Errorf("run states: %w", err)
+			var adminElections []admin.Election
+			var elections []*depgraph.Election
+			for _, name := range sortedNames(electionArgs) {
+				liveArgs := cmdargs.NewLive(electionArgs[name])
+				electionMetrics := metrics.Election(name)
+				reloader := reload.New(logger.With("election", name), liveArgs, config.Path(cmd.Flags()), func() (cmdargs.RunArgs, error) {
+					return reloadRunArgs(cmd.Flags(), name)
+				}, ticker.GetTicker(), electionMetrics)
+				eg.Go(func() error {
+					return reloader.Run(ctx)
+				})
+				if len(elections) == 0 { // log options are process wide, any election follows them
+					eg.Go(func() error {
+						return logging.FollowLevel(ctx, liveArgs, dg.GetLogLevel())
+					})
+				}
+
+				control := states.NewControl()
+				election, err := dg.GetElection(name, shared, liveArgs, electionMetrics, control, ticker.GetTicker())
+				if err != nil {
+					return fmt.Errorf("get election %q: %w", name, err)
+				}
+				elections = append(elections, election)
+				adminElection := admin.Election{
+					Name:      name,
+					Source:    election.Runner,
+					Connector: election.Connector,
+					Control:   control,
+					Options:   liveArgs,
+				}
+				if election.Partitions != nil {
+					adminElection.Partitions = election.Partitions
+				}
+				adminElections = append(adminElections, adminElection)
 			}
+			admin.RegisterHealth(adminSrv, adminElections)
+			admin.RegisterAPI(adminSrv, adminElections, args.AdminControlToken)
 
-			if ctx.Err() != nil {
-				_ = eg.Wait()
-				return ctx.Err()
+			logger.Info("app started init state", slog.Int("elections", len(elections)))
+			for _, election := range elections {
+				eg.Go(func() error {
+					err := election.Runner.Run(ctx, election.InitState)
+					switch {
+					case err == nil:
+						return nil
+					case election.Name == "":
+						return fmt.Errorf("run states: %w", err)
+					case ctx.Err() != nil: // process shuts down
+						return fmt.Errorf("run election %s states: %w", election.Name, err)
+					}
+					// elections are independent, failure of one only fails its probes
+					logger.Error("election stopped", slog.String("election", election.Name), slog.String("error", err.Error()))
+					return nil
+				})
+				eg.Go(func() error {
+					return election.Members.Run(ctx)
+				})
+				if election.Partitions != nil {
+					eg.Go(func() error {
+						return election.Partitions.Run(ctx)
+					})
+				}
 			}
+
 			return eg.Wait()
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&(cmdArgs.ZookeeperServers), "zk-servers", "s", []string{"zoo1:2181", "zoo2:2182", "zoo3:2183"}, "Set the zookeeper servers.")
-	cmd.Flags().DurationVarP(&(cmdArgs.LeaderTimeout), "leader-timeout", "l", 300*time.Millisecond, "Set the leader file write timeout.")
-	cmd.Flags().DurationVarP(&(cmdArgs.MaxDeadLeaderTimeout), "dead-leader-timeout", "t", 400*time.Millisecond, "Set the max timeout zookeper will wait for dead leader.")
-	cmd.Flags().DurationVarP(&(cmdArgs.FailoverQuickRetryTimeout), "failover-quick-retry-timeout", "q", 50*time.Millisecond, "Set retry frequency to reconnect to zookeper as dead leader.")
-	cmd.Flags().DurationVarP(&(cmdArgs.FailoverSlowRetryStep), "failover-slow-retry-step", "r", 500*time.Millisecond, "Set step timeout of retrying to return to attemper state .")
-	cmd.Flags().DurationVarP(&(cmdArgs.FailoverMaxStateDuration), "failover-max-duration", "w", 10*time.Second, "Set max failover duration as a state.")
-	cmd.Flags().DurationVarP(&(cmdArgs.AttempterTimeout), "attempter-timeout", "a", 300*time.Millisecond, "Set the attempt to become leader timeout.")
-	cmd.Flags().StringVarP(&(cmdArgs.ElectionFileDir), "election-file-dir", "f", "/election", "Set the path to write file to become leader.")
-	cmd.Flags().StringVarP(&(cmdArgs.LeaderFileDir), "leader-file-dir", "d", "/data", "Set the path to write files as leader.")
-	cmd.Flags().IntVarP(&(cmdArgs.StorageCapacity), "storage-capacity", "c", 5, "Set max amount of files in leader dir.")
+	bindRunFlags(cmd.Flags(), &cmdArgs)
 
 	return cmd, nil
 }
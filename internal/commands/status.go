@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/inspect"
+	"github.com/spf13/cobra"
+)
+
+func InitStatusCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Prints cluster-wide election state",
+		Long: `This command connects to zookeeper, reads election node and leader dir
+		and prints who is leader, leader files with their ages and detected anomalies`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			args, err := loadZkArgs(cmd.Flags(), &cmdArgs)
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q", output)
+			}
+
+			logger := cliLogger()
+			conn, err := backend.NewZkConnector(logger, cmdargs.NewLive(args), nil).Connect(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("connect to zookeeper: %w", err)
+			}
+			defer conn.Close()
+
+			report, err := inspect.Inspect(conn, args, time.Now())
+			if err != nil {
+				return fmt.Errorf("inspect election: %w", err)
+			}
+
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			return printReport(cmd.OutOrStdout(), report, time.Now())
+		},
+	}
+
+	bindZkFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Set the output format: text or json.")
+
+	return cmd, nil
+}
+
+// cliLogger is a logger for one-shot commands: their output goes to stdout, so logs are written to stderr
+func cliLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+}
+
+func printReport(w io.Writer, report inspect.Report, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if report.Namespace != "" {
+		fmt.Fprintf(tw, "Namespace:\t%s\n", report.Namespace)
+	}
+	ld := report.Leader
+	switch {
+	case !ld.Present:
+		fmt.Fprintf(tw, "Leader:\tnone (%s is absent)\n", report.ElectionPath)
+	case ld.Identity != nil:
+		fmt.Fprintf(tw, "Leader:\t%s\n", ld.Identity.NodeID)
+		if ld.Identity.AdminAddr != "" {
+			fmt.Fprintf(tw, "Admin address:\t%s\n", ld.Identity.AdminAddr)
+		}
+		fmt.Fprintf(tw, "Elected:\t%s (%s ago)\n", ld.Identity.Elected.Format(time.RFC3339), now.Sub(ld.Identity.Elected).Round(time.Second))
+	default:
+		fmt.Fprintf(tw, "Leader:\tunknown, election node has no identity\n")
+	}
+	if ld.Present {
+		fmt.Fprintf(tw, "Session owner:\t0x%x\n", ld.SessionID)
+	}
+
+	fmt.Fprintf(tw, "\nStorage %s (%d files):\n", report.StoragePath, len(report.Storage))
+	if len(report.Storage) != 0 {
+		fmt.Fprintln(tw, "  NAME\tCREATED\tAGE")
+		for _, f := range report.Storage {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.Name, f.Created.Format(time.RFC3339Nano), time.Duration(f.AgeSeconds*float64(time.Second)).Round(time.Millisecond))
+		}
+	}
+
+	if len(report.Anomalies) == 0 {
+		fmt.Fprintln(tw, "\nNo anomalies detected")
+	} else {
+		fmt.Fprintln(tw, "\nAnomalies:")
+		for _, a := range report.Anomalies {
+			fmt.Fprintf(tw, "  - %s\n", a)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/watch"
+	"github.com/spf13/cobra"
+)
+
+func InitWatchCommand() (*cobra.Command, error) {
+	cmdArgs := cmdargs.RunArgs{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Streams leadership changes",
+		Long: `This command subscribes to election node and leader dir and prints a line each time
+		leader is elected or lost and each time leader writes a new file, with time spent without leader`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			args, err := loadZkArgs(cmd.Flags(), &cmdArgs)
+			if err != nil {
+				return fmt.Errorf("load args: %w", err)
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q", output)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logger := cliLogger()
+			conn, err := backend.NewZkConnector(logger, cmdargs.NewLive(args), nil).Connect(ctx)
+			if err != nil {
+				return fmt.Errorf("connect to zookeeper: %w", err)
+			}
+			defer conn.Close()
+
+			out := cmd.OutOrStdout()
+			emit := func(ev watch.Event) error {
+				return printEvent(out, ev)
+			}
+			if output == "json" {
+				enc := json.NewEncoder(out)
+				emit = func(ev watch.Event) error {
+					return enc.Encode(ev)
+				}
+			}
+			return watch.New(logger, conn, args, ticker.GetTicker()).Run(ctx, emit)
+		},
+	}
+
+	bindZkFlags(cmd.Flags(), &cmdArgs)
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Set the output format: text or json lines.")
+
+	return cmd, nil
+}
+
+func printEvent(w io.Writer, ev watch.Event) error {
+	line := ev.Time.Format(time.RFC3339Nano) + " " + ev.Type
+	if ev.Leader != nil {
+		switch {
+		case !ev.Leader.Present:
+			line += " leader=none"
+		case ev.Leader.Identity != nil:
+			line += fmt.Sprintf(" leader=%s session=0x%x", ev.Leader.Identity.NodeID, ev.Leader.SessionID)
+		default:
+			line += fmt.Sprintf(" leader=unknown session=0x%x", ev.Leader.SessionID)
+		}
+	}
+	if ev.File != "" {
+		line += " file=" + ev.File
+	}
+	if ev.GapSeconds != nil {
+		line += " gap=" + time.Duration(*ev.GapSeconds*float64(time.Second)).Round(time.Millisecond).String()
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to env names of flags: --some-flag -> ELECTION_SOME_FLAG
+const EnvPrefix = "ELECTION"
+
+// ConfigFlag is the flag with path to config file, it's never read from the file itself
+const ConfigFlag = "config"
+
+// ElectionsKey is the config file list of named elections run by one process
+const ElectionsKey = "elections"
+
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Sources maps flag name to the place its effective value was taken from
+type Sources map[string]Source
+
+func EnvName(flag string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Path returns config file path from flag or env, empty if config file is not used
+func Path(fs *pflag.FlagSet) string {
+	if fl := fs.Lookup(ConfigFlag); fl != nil && fl.Changed {
+		return fl.Value.String()
+	}
+	return os.Getenv(EnvName(ConfigFlag))
+}
+
+// Load fills flags which weren't set on command line from env and config file.
+// Precedence is flags > env > file > defaults.
+func Load(fs *pflag.FlagSet) (Sources, error) {
+	var file map[string]any
+	if pth := Path(fs); pth != "" {
+		var err error
+		if file, err = ReadFile(pth); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for key := range file {
+		if key == ElectionsKey {
+			continue
+		}
+		if fl := fs.Lookup(key); fl == nil || key == ConfigFlag {
+			errs = append(errs, fmt.Errorf("config file: unknown option %q", key))
+		}
+	}
+
+	sources := Sources{}
+	fs.VisitAll(func(fl *pflag.Flag) {
+		if fl.Name == "help" {
+			return
+		}
+		if fl.Changed {
+			sources[fl.Name] = SourceFlag
+			return
+		}
+		if env, ok := os.LookupEnv(EnvName(fl.Name)); ok {
+			if err := fl.Value.Set(env); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", EnvName(fl.Name), err))
+			}
+			sources[fl.Name] = SourceEnv
+			return
+		}
+		if val, ok := file[fl.Name]; ok && fl.Name != ConfigFlag {
+			if err := setFromFile(fl, val); err != nil {
+				errs = append(errs, fmt.Errorf("config file option %q: %w", fl.Name, err))
+			}
+			sources[fl.Name] = SourceFile
+			return
+		}
+		sources[fl.Name] = SourceDefault
+	})
+
+	return sources, errors.Join(errs...)
+}
+
+// Election is a named election from config file, its options override global ones
+type Election struct {
+	Name    string
+	Options map[string]any
+}
+
+// Elections returns named elections listed in config file, nil if there are none
+func Elections(fs *pflag.FlagSet) ([]Election, error) {
+	pth := Path(fs)
+	if pth == "" {
+		return nil, nil
+	}
+	file, err := ReadFile(pth)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := file[ElectionsKey]
+	if !ok {
+		return nil, nil
+	}
+	var list []map[string]any
+	switch val := val.(type) {
+	case []map[string]any: // toml array of tables
+		list = val
+	case []any:
+		for i, item := range val {
+			opts, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("config file: %s[%d] must be an object", ElectionsKey, i)
+			}
+			list = append(list, opts)
+		}
+	default:
+		return nil, fmt.Errorf("config file: %s must be a list", ElectionsKey)
+	}
+
+	res := make([]Election, 0, len(list))
+	for i, item := range list {
+		opts := make(map[string]any, len(item))
+		for k, v := range item {
+			opts[k] = v
+		}
+		name, ok := opts["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("config file: %s[%d] has no name", ElectionsKey, i)
+		}
+		delete(opts, "name")
+		res = append(res, Election{Name: name, Options: opts})
+	}
+	return res, nil
+}
+
+// Apply sets election options to flags over any other source
+func (e Election) Apply(fs *pflag.FlagSet) error {
+	var errs []error
+	for key, val := range e.Options {
+		fl := fs.Lookup(key)
+		if fl == nil || key == ConfigFlag {
+			errs = append(errs, fmt.Errorf("election %s: unknown option %q", e.Name, key))
+			continue
+		}
+		if err := setFromFile(fl, val); err != nil {
+			errs = append(errs, fmt.Errorf("election %s option %q: %w", e.Name, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReadFile parses config file in format chosen by its extension: yaml, toml or json
+func ReadFile(pth string) (map[string]any, error) {
+	data, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	res := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(pth)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &res)
+	case ".toml":
+		err = toml.Unmarshal(data, &res)
+	case ".json":
+		err = json.Unmarshal(data, &res)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension %q, use yaml, toml or json", pth, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", pth, err)
+	}
+	return res, nil
+}
+
+func setFromFile(fl *pflag.Flag, val any) error {
+	list, isList := val.([]any)
+	if !isList {
+		if _, isMap := val.(map[string]any); isMap {
+			return errors.New("nested objects are not supported")
+		}
+		return fl.Value.Set(scalar(val))
+	}
+
+	strs := make([]string, 0, len(list))
+	for _, v := range list {
+		strs = append(strs, scalar(v))
+	}
+	if sv, ok := fl.Value.(pflag.SliceValue); ok {
+		return sv.Replace(strs)
+	}
+	return fl.Value.Set(strings.Join(strs, ","))
+}
+
+// scalar formats option value from file, json numbers are float64 and must not get exponent form
+func scalar(val any) string {
+	if f, ok := val.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(val)
+}
+
+// Value returns flag value in a form that can be passed back to Set
+func Value(fl *pflag.Flag) string {
+	if sv, ok := fl.Value.(pflag.SliceValue); ok {
+		return strings.Join(sv.GetSlice(), ",")
+	}
+	return fl.Value.String()
+}
+
+// Names returns sorted names of flags that have sources
+func (s Sources) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
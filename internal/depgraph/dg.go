@@ -1,16 +1,27 @@
 package depgraph
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"os"
 	"sync"
+	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/journal"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/logging"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/tracing"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/partition"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/registry"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/init_s"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type dgEntity[T any] struct {
@@ -29,42 +40,190 @@ func (e *dgEntity[T]) get(init func() (T, error)) (T, error) {
 	return e.value, nil
 }
 
+// traceFlushTimeout limits export of buffered spans on close
+const traceFlushTimeout = 5 * time.Second
+
 type DepGraph struct {
 	logger      *dgEntity[*slog.Logger]
-	stateRunner *dgEntity[*run.LoopRunner]
-	InitState   *dgEntity[*init_s.State]
+	logFields   *dgEntity[*logging.Fields]
+	logLevel    *slog.LevelVar
+	logCloser   io.Closer
+	journal     *dgEntity[*journal.Journal]
+	tracer      *dgEntity[trace.TracerProvider]
+	traceStop   func(context.Context) error
+	connector   *dgEntity[*backend.SharedConnector]
+	electionsMu sync.Mutex
+	elections   map[string]*dgEntity[*Election]
 }
 
 func New() *DepGraph {
 	return &DepGraph{
-		logger:      &dgEntity[*slog.Logger]{},
-		stateRunner: &dgEntity[*run.LoopRunner]{},
-		InitState:   &dgEntity[*init_s.State]{},
+		logger:    &dgEntity[*slog.Logger]{},
+		logFields: &dgEntity[*logging.Fields]{},
+		logLevel:  &slog.LevelVar{},
+		journal:   &dgEntity[*journal.Journal]{},
+		tracer:    &dgEntity[trace.TracerProvider]{},
+		connector: &dgEntity[*backend.SharedConnector]{},
+		elections: map[string]*dgEntity[*Election]{},
 	}
 }
 
-func (dg *DepGraph) GetLogger() (*slog.Logger, error) {
+// GetLogger creates logger from log options, they are taken from the first call only
+func (dg *DepGraph) GetLogger(opts cmdargs.RunArgs) (*slog.Logger, error) {
 	return dg.logger.get(func() (*slog.Logger, error) {
-		return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{})), nil
+		fields, err := dg.GetLogFields(opts)
+		if err != nil {
+			return nil, fmt.Errorf("get log fields: %w", err)
+		}
+		logger, closer, err := logging.New(logging.Options{
+			Level:      opts.LogLevel,
+			Format:     opts.LogFormat,
+			File:       opts.LogFile,
+			MaxSizeMB:  opts.LogMaxSizeMB,
+			MaxBackups: opts.LogMaxBackups,
+		}, dg.logLevel, fields)
+		if err != nil {
+			return nil, err
+		}
+		dg.logCloser = closer
+		return logger, nil
+	})
+}
+
+// GetLogFields returns fields of process wide logs, elections attach their own ones
+func (dg *DepGraph) GetLogFields(opts cmdargs.RunArgs) (*logging.Fields, error) {
+	return dg.logFields.get(func() (*logging.Fields, error) {
+		return logging.NewFields(opts.NodeID, ""), nil
+	})
+}
+
+// GetLogLevel returns level of the logger, it can be changed while app runs
+func (dg *DepGraph) GetLogLevel() *slog.LevelVar {
+	return dg.logLevel
+}
+
+// GetJournal opens transition journal, it's nil if journal file isn't set
+func (dg *DepGraph) GetJournal(opts cmdargs.RunArgs) (*journal.Journal, error) {
+	return dg.journal.get(func() (*journal.Journal, error) {
+		if opts.JournalFile == "" {
+			return nil, nil
+		}
+		return journal.Open(opts.JournalFile, opts.JournalMaxSizeMB, opts.JournalMaxBackups)
 	})
 }
 
-func (dg *DepGraph) GetInitState(ticker ticker.Ticker, opts cmdargs.RunArgs) (*init_s.State, error) {
-	return dg.InitState.get(func() (*init_s.State, error) {
-		logger, err := dg.GetLogger()
+// GetTracerProvider creates tracer provider from trace options, they are taken from the first call only
+func (dg *DepGraph) GetTracerProvider(opts cmdargs.RunArgs) (trace.TracerProvider, error) {
+	return dg.tracer.get(func() (trace.TracerProvider, error) {
+		provider, stop, err := tracing.New(tracing.Options{Exporter: opts.TraceExporter, File: opts.TraceFile, NodeID: opts.NodeID})
+		if err != nil {
+			return nil, err
+		}
+		dg.traceStop = stop
+		return provider, nil
+	})
+}
+
+// Close releases resources held by created entities and flushes buffered spans
+func (dg *DepGraph) Close() error {
+	var errs []error
+	if dg.traceStop != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+		defer cancel()
+		errs = append(errs, dg.traceStop(ctx))
+	}
+	if dg.journal.value != nil {
+		errs = append(errs, dg.journal.value.Close())
+	}
+	if dg.logCloser != nil {
+		errs = append(errs, dg.logCloser.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// GetSharedConnector creates connector of the zookeeper session shared by all elections of the process,
+// opts and metr are process wide ones: connection flags can't be set per election and session metrics have no election label
+func (dg *DepGraph) GetSharedConnector(opts *cmdargs.Live, metr *metrics.Metrics) (*backend.SharedConnector, error) {
+	return dg.connector.get(func() (*backend.SharedConnector, error) {
+		logger, err := dg.GetLogger(opts.Get())
 		if err != nil {
 			return nil, fmt.Errorf("get logger: %w", err)
 		}
-		return init_s.New(logger, ticker, opts), nil
+		fields, err := dg.GetLogFields(opts.Get())
+		if err != nil {
+			return nil, fmt.Errorf("get log fields: %w", err)
+		}
+		logger = logger.With("subsystem", "ZkConnector")
+		connector := backend.NewSharedConnector(backend.NewZkConnector(logger, opts, backend.ObserveSessionEvents(metr)))
+		fields.SetSessionSource(func() int64 {
+			if conn := connector.Current(); conn != nil {
+				return conn.SessionID()
+			}
+			return 0
+		})
+		return connector, nil
 	})
 }
 
-func (dg *DepGraph) GetRunner(metr *metrics.Metrics) (run.Runner, error) {
-	return dg.stateRunner.get(func() (*run.LoopRunner, error) {
-		logger, err := dg.GetLogger()
+// Election is the automaton of one election with its dependencies
+type Election struct {
+	Name       string
+	Connector  backend.Connector
+	Runner     run.Runner
+	InitState  *init_s.State
+	Members    *registry.Registrar
+	Partitions *partition.Owner // nil if partitioning is disabled
+}
+
+// GetElection creates automaton of named election, empty name is used by the only election of the process.
+// Elections share zookeeper session of shared connector, logger, journal and tracer provider, metrics are created by caller per election.
+func (dg *DepGraph) GetElection(name string, shared *backend.SharedConnector, opts *cmdargs.Live, metr *metrics.Metrics, control *states.Control, ticker ticker.Ticker) (*Election, error) {
+	dg.electionsMu.Lock()
+	entity, ok := dg.elections[name]
+	if !ok {
+		entity = &dgEntity[*Election]{}
+		dg.elections[name] = entity
+	}
+	dg.electionsMu.Unlock()
+
+	return entity.get(func() (*Election, error) {
+		args := opts.Get()
+		logger, err := dg.GetLogger(args)
 		if err != nil {
 			return nil, fmt.Errorf("get logger: %w", err)
 		}
-		return run.NewLoopRunner(logger, metr), nil
+		fields := logging.NewFields(args.NodeID, name)
+		logger = logging.ForFields(logger, fields)
+
+		scope := &tracing.Scope{}
+		var connector backend.Connector = backend.NewInstrumentedConnector(shared, metr)
+		connector = backend.NewTracedConnector(connector, scope)
+		fields.SetSessionSource(func() int64 {
+			if conn := connector.Current(); conn != nil {
+				return conn.SessionID()
+			}
+			return 0
+		})
+
+		journal, err := dg.GetJournal(args)
+		if err != nil {
+			return nil, fmt.Errorf("get journal: %w", err)
+		}
+		provider, err := dg.GetTracerProvider(args)
+		if err != nil {
+			return nil, fmt.Errorf("get tracer provider: %w", err)
+		}
+
+		election := &Election{
+			Name:      name,
+			Connector: connector,
+			Runner:    run.NewLoopRunner(logger, metr, fields, journal, provider.Tracer(tracing.Name), scope, control, opts),
+			InitState: init_s.New(logger, connector, control, ticker, opts),
+		}
+		election.Members = registry.NewRegistrar(logger, connector, election.Runner, control, ticker, opts)
+		if args.Partitions > 0 {
+			election.Partitions = partition.NewOwner(logger, connector, election.Runner, metr, ticker, opts)
+		}
+		return election, nil
 	})
 }
@@ -0,0 +1,129 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/rotate"
+)
+
+// Entry is one state transition of the automaton, it's written to journal as a json line
+type Entry struct {
+	Time      time.Time `json:"t"`
+	Election  string    `json:"election,omitempty"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	SessionID int64     `json:"sid,omitempty"`
+	Epoch     int64     `json:"epoch,omitempty"`
+}
+
+// Journal appends transitions to a local file rotated by size, nil journal records nothing
+type Journal struct {
+	file *rotate.File
+}
+
+func Open(path string, maxSizeMB, maxBackups int) (*Journal, error) {
+	file, err := rotate.Open(path, int64(maxSizeMB)<<20, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{file: file}, nil
+}
+
+// Append writes entry and syncs it to disk, transitions are rare, so every one of them is made durable
+func (j *Journal) Append(e Entry) error {
+	if j == nil {
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write entry: %w", err)
+	}
+	return j.file.Sync()
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// Filter selects journal entries, zero fields match everything
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	State    string // entries from or to this state
+	Election string
+	Last     int // only this many latest entries
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	case f.State != "" && e.From != f.State && e.To != f.State:
+		return false
+	case f.Election != "" && e.Election != f.Election:
+		return false
+	}
+	return true
+}
+
+// Read returns entries of journal and its backups from the oldest to the newest.
+// Lines that can't be parsed, like the one cut by crash, are skipped.
+func Read(path string, maxBackups int, filter Filter) ([]Entry, error) {
+	var res []Entry
+	for i := maxBackups; i >= 0; i-- {
+		pth := path
+		if i != 0 {
+			pth = rotate.Backup(path, i)
+		}
+		entries, err := readFile(pth, filter)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, entries...)
+	}
+	if filter.Last > 0 && len(res) > filter.Last {
+		res = res[len(res)-filter.Last:]
+	}
+	return res, nil
+}
+
+func readFile(path string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var res []Entry
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		if filter.match(e) {
+			res = append(res, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return res, nil
+}
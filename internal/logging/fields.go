@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Fields describe the running node, they are attached to every log record.
+// Session, state and epoch change while node runs, empty ones are omitted.
+type Fields struct {
+	nodeID   string
+	election string
+	session  atomic.Pointer[func() int64]
+	state    atomic.Pointer[string]
+	epoch    atomic.Int64
+}
+
+// NewFields creates fields of election automaton, election is empty for the only election of the process
+func NewFields(nodeID, election string) *Fields {
+	return &Fields{nodeID: nodeID, election: election}
+}
+
+func (f *Fields) Election() string {
+	return f.election
+}
+
+// SetSessionSource sets func returning current zookeeper session id, 0 if there is no session
+func (f *Fields) SetSessionSource(session func() int64) {
+	f.session.Store(&session)
+}
+
+func (f *Fields) SetState(state string) {
+	f.state.Store(&state)
+}
+
+// SetEpoch sets leadership epoch - creation zxid of election node this node holds, 0 if it isn't leader
+func (f *Fields) SetEpoch(epoch int64) {
+	f.epoch.Store(epoch)
+}
+
+// SessionID returns current zookeeper session id, 0 if there is no session
+func (f *Fields) SessionID() int64 {
+	if session := f.session.Load(); session != nil {
+		return (*session)()
+	}
+	return 0
+}
+
+func (f *Fields) Epoch() int64 {
+	return f.epoch.Load()
+}
+
+func (f *Fields) attrs() []slog.Attr {
+	res := make([]slog.Attr, 0, 5)
+	if f.nodeID != "" {
+		res = append(res, slog.String("node_id", f.nodeID))
+	}
+	if f.election != "" {
+		res = append(res, slog.String("election", f.election))
+	}
+	if id := f.SessionID(); id != 0 {
+		res = append(res, slog.String("session_id", fmt.Sprintf("0x%x", id)))
+	}
+	if state := f.state.Load(); state != nil {
+		res = append(res, slog.String("state", *state))
+	}
+	if epoch := f.Epoch(); epoch != 0 {
+		res = append(res, slog.Int64("epoch", epoch))
+	}
+	return res
+}
+
+type fieldsKey struct{}
+
+// WithFields makes fields available to code that gets only context, like automaton states
+func WithFields(ctx context.Context, f *Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// FromContext returns fields put by WithFields, unattached fields if there are none
+func FromContext(ctx context.Context) *Fields {
+	if f, ok := ctx.Value(fieldsKey{}).(*Fields); ok {
+		return f
+	}
+	return &Fields{}
+}
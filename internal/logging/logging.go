@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/rotate"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configure where and how logs are written
+type Options struct {
+	Level      string
+	Format     string
+	File       string // empty means stdout
+	MaxSizeMB  int    // file is rotated when it grows over it, 0 disables rotation
+	MaxBackups int
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var res slog.Level
+	if err := res.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+	return res, nil
+}
+
+// New creates logger which adds fields to every record, level can be changed later through the level var.
+// Returned closer closes the log file.
+func New(opts Options, level *slog.LevelVar, fields *Fields) (*slog.Logger, io.Closer, error) {
+	lvl, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	level.Set(lvl)
+
+	var out io.Writer = os.Stdout
+	var closer io.Closer = io.NopCloser(nil)
+	if opts.File != "" {
+		file, err := rotate.Open(opts.File, int64(opts.MaxSizeMB)<<20, opts.MaxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		out, closer = file, file
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatText:
+		handler = slog.NewTextHandler(out, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	return slog.New(&fieldsHandler{Handler: handler, fields: fields}), closer, nil
+}
+
+// fieldsHandler adds current fields to every record
+type fieldsHandler struct {
+	slog.Handler
+	fields *Fields
+}
+
+// Handle also adds ids of the span in ctx, so logs can be matched with traces
+func (h *fieldsHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(h.fields.attrs()...)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// ForFields returns logger which attaches other fields, it's used to log on behalf of another election
+func ForFields(logger *slog.Logger, fields *Fields) *slog.Logger {
+	if h, ok := logger.Handler().(*fieldsHandler); ok {
+		return slog.New(&fieldsHandler{Handler: h.Handler, fields: fields})
+	}
+	return logger
+}
+
+func (h *fieldsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &fieldsHandler{Handler: h.Handler.WithAttrs(attrs), fields: h.fields}
+}
+
+func (h *fieldsHandler) WithGroup(name string) slog.Handler {
+	return &fieldsHandler{Handler: h.Handler.WithGroup(name), fields: h.fields}
+}
+
+// FollowLevel applies reloaded log level until ctx is done
+func FollowLevel(ctx context.Context, opts *cmdargs.Live, level *slog.LevelVar) error {
+	for {
+		changed := opts.Changed()
+		if lvl, err := ParseLevel(opts.Get().LogLevel); err == nil {
+			level.Set(lvl)
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -7,17 +7,60 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"golang.org/x/sync/errgroup"
 )
 
+// Metrics of the process. Election ones are nil until Election is called,
+// zookeeper session ones are shared by all elections as they share the session.
 type Metrics struct {
-	AmtStateChanges   prometheus.Counter
-	CurState          prometheus.Gauge
-	CurStateStartTime prometheus.Gauge
+	AmtStateChanges     prometheus.Counter
+	CurState            prometheus.Gauge
+	CurStateStartTime   prometheus.Gauge
+	ConfigReloads       *prometheus.CounterVec
+	State               *prometheus.GaugeVec
+	StateDuration       *prometheus.HistogramVec
+	StateTransitions    *prometheus.CounterVec
+	LeadershipGained    prometheus.Counter
+	LeadershipLost      prometheus.Counter
+	LeadershipFlaps     prometheus.Counter
+	ContestHoldUntil    prometheus.Gauge
+	ZkOpDuration        *prometheus.HistogramVec
+	ZkOpErrors          *prometheus.CounterVec
+	ZkInFlight          *prometheus.GaugeVec
+	ZkSessionState      *prometheus.GaugeVec
+	ZkReconnects        prometheus.Counter
+	PartitionsOwned     prometheus.Gauge
+	PartitionRebalances prometheus.Counter
+
+	reg prometheus.Registerer
 }
 
 func newMetrics(reg prometheus.Registerer) *Metrics {
 	m := &Metrics{
+		ZkSessionState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "zk_session_state",
+			Help: "Zookeeper client session state: 1 for current one, 0 for others.",
+		}, []string{"state"}),
+		ZkReconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "zk_reconnect_attempts",
+			Help: "Amount of zookeeper client attempts to reconnect within a session.",
+		}),
+		reg: reg,
+	}
+
+	reg.MustRegister(m.ZkSessionState)
+	reg.MustRegister(m.ZkReconnects)
+
+	return m
+}
+
+// Election registers metrics of one election automaton, they are labeled with election name.
+// Empty name is used by the only election of the process, its metrics have no election label.
+func (m *Metrics) Election(name string) *Metrics {
+	reg := m.reg
+	if name != "" {
+		reg = prometheus.WrapRegistererWith(prometheus.Labels{"election": name}, m.reg)
+	}
+	e := &Metrics{
 		AmtStateChanges: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "amt_state_changes",
 			Help: "Amount of states changes.",
@@ -34,37 +77,102 @@ func newMetrics(reg prometheus.Registerer) *Metrics {
 				Help: "Start time of current running state.",
 			},
 		),
+		ConfigReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "config_reloads",
+			Help: "Amount of config reloads by result: applied, unchanged, rejected or failed.",
+		}, []string{"result"}),
+		State: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "state",
+			Help: "Current running state: 1 for running one, 0 for others.",
+		}, []string{"name"}),
+		StateDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "state_duration_seconds",
+			Help:    "Time spent in state before switching to the next one.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 3, 12),
+		}, []string{"state"}),
+		StateTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "state_transitions",
+			Help: "Amount of transitions between states with the reason of transition.",
+		}, []string{"from", "to", "reason"}),
+		LeadershipGained: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "leadership_acquisitions",
+			Help: "Amount of times node became leader.",
+		}),
+		LeadershipLost: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "leadership_losses",
+			Help: "Amount of times node stopped being leader.",
+		}),
+		LeadershipFlaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "leadership_flaps",
+			Help: "Amount of times node backed off contesting as it lost leadership too often.",
+		}),
+		ContestHoldUntil: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "contest_hold_until",
+			Help: "Unix time node holds off contesting until after the last leadership loss.",
+		}),
+		ZkOpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "zk_operation_duration_seconds",
+			Help:    "Latency of zookeeper operations.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, []string{"op"}),
+		ZkOpErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "zk_operation_errors",
+			Help: "Amount of failed zookeeper operations by error class.",
+		}, []string{"op", "class"}),
+		ZkInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "zk_operations_in_flight",
+			Help: "Amount of zookeeper operations waiting for response.",
+		}, []string{"op"}),
+		PartitionsOwned: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "partitions_owned",
+			Help: "Amount of partitions owned by the node.",
+		}),
+		PartitionRebalances: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "partition_rebalances",
+			Help: "Amount of changes of partitions assigned to the node.",
+		}),
+		ZkSessionState: m.ZkSessionState,
+		ZkReconnects:   m.ZkReconnects,
+		reg:            m.reg,
 	}
 
-	reg.MustRegister(m.AmtStateChanges)
-	reg.MustRegister(m.CurState)
-	reg.MustRegister(m.CurStateStartTime)
+	reg.MustRegister(e.AmtStateChanges)
+	reg.MustRegister(e.CurState)
+	reg.MustRegister(e.CurStateStartTime)
+	reg.MustRegister(e.ConfigReloads)
+	reg.MustRegister(e.State)
+	reg.MustRegister(e.StateDuration)
+	reg.MustRegister(e.StateTransitions)
+	reg.MustRegister(e.LeadershipGained)
+	reg.MustRegister(e.LeadershipLost)
+	reg.MustRegister(e.LeadershipFlaps)
+	reg.MustRegister(e.ContestHoldUntil)
+	reg.MustRegister(e.ZkOpDuration)
+	reg.MustRegister(e.ZkOpErrors)
+	reg.MustRegister(e.ZkInFlight)
+	reg.MustRegister(e.PartitionsOwned)
+	reg.MustRegister(e.PartitionRebalances)
 
-	return m
+	return e
 }
 
-func InitPrometheus(ctx context.Context, logger *slog.Logger, eg *errgroup.Group) *Metrics {
+// Router is where metrics handler is registered
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// InitPrometheus registers metrics handler, all metrics are labeled with zookeeper namespace if it's set
+func InitPrometheus(ctx context.Context, logger *slog.Logger, router Router, namespace string) *Metrics {
 	logger = logger.With("subsystem", "Prometheus")
 	logger.LogAttrs(ctx, slog.LevelInfo, "Start initializing prometheus")
 	reg := prometheus.NewRegistry()
 
-	m := newMetrics(reg)
-
-	srv := &http.Server{Addr: ":8080"}
-	eg.Go(func() error {
-		<-ctx.Done()
-		logger.LogAttrs(ctx, slog.LevelInfo, "Prometheus http server is shutting down")
-		return srv.Shutdown(ctx)
-	})
-
-	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
-	eg.Go(func() error {
-		if err := srv.ListenAndServe(); err != nil {
-			logger.LogAttrs(ctx, slog.LevelError, "Prometheus http server fell down: "+err.Error())
-			return err
-		}
-		return nil
-	})
+	var wrapped prometheus.Registerer = reg
+	if namespace != "" {
+		wrapped = prometheus.WrapRegistererWith(prometheus.Labels{"namespace": namespace}, reg)
+	}
+	m := newMetrics(wrapped)
+	router.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
 
 	return m
 }
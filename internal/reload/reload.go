@@ -0,0 +1,111 @@
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+)
+
+// pollInterval is how often config file is checked, polling survives configmap symlink swaps unlike inotify
+const pollInterval = 2 * time.Second
+
+// Watcher reloads run args on SIGHUP and on config file change and applies them to Live args
+type Watcher struct {
+	logger  *slog.Logger
+	live    *cmdargs.Live
+	path    string
+	load    func() (cmdargs.RunArgs, error)
+	ticker  ticker.Ticker
+	metrics *metrics.Metrics
+}
+
+func New(logger *slog.Logger, live *cmdargs.Live, path string, load func() (cmdargs.RunArgs, error), ticker ticker.Ticker, metrics *metrics.Metrics) *Watcher {
+	logger = logger.With("subsystem", "ConfigReloader")
+	return &Watcher{
+		logger:  logger,
+		live:    live,
+		path:    path,
+		load:    load,
+		ticker:  ticker,
+		metrics: metrics,
+	}
+}
+
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tckr <-chan time.Time
+	var lastSum [sha256.Size]byte
+	if w.path != "" {
+		var stTckr func()
+		tckr, stTckr = w.ticker.GetTicker(pollInterval)
+		defer stTckr()
+		lastSum, _ = fileSum(w.path)
+	}
+
+	for {
+		select {
+		case <-hup:
+			w.reload(ctx, "sighup")
+		case <-tckr:
+			sum, err := fileSum(w.path)
+			if err != nil {
+				w.logger.LogAttrs(ctx, slog.LevelWarn, "Failed to check config file", slog.String("path", w.path), slog.String("error", err.Error()))
+				continue
+			}
+			if sum != lastSum {
+				lastSum = sum
+				w.reload(ctx, "file")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (w *Watcher) reload(ctx context.Context, trigger string) {
+	w.logger.LogAttrs(ctx, slog.LevelInfo, "Reloading config", slog.String("trigger", trigger))
+	args, err := w.load()
+	if err != nil {
+		w.logger.LogAttrs(ctx, slog.LevelError, "Failed to load config, keep running with current one", slog.String("error", err.Error()))
+		w.metrics.ConfigReloads.WithLabelValues("failed").Inc()
+		return
+	}
+
+	changed, err := w.live.Update(args)
+	switch {
+	case errors.Is(err, cmdargs.ErrUnsafeChange):
+		w.logger.LogAttrs(ctx, slog.LevelError, "Config reload rejected, restart the node to apply it", slog.String("error", err.Error()))
+		w.metrics.ConfigReloads.WithLabelValues("rejected").Inc()
+	case err != nil:
+		w.logger.LogAttrs(ctx, slog.LevelError, "Failed to apply config", slog.String("error", err.Error()))
+		w.metrics.ConfigReloads.WithLabelValues("failed").Inc()
+	case len(changed) == 0:
+		w.logger.LogAttrs(ctx, slog.LevelInfo, "Config reloaded, nothing changed")
+		w.metrics.ConfigReloads.WithLabelValues("unchanged").Inc()
+	default:
+		w.logger.LogAttrs(ctx, slog.LevelInfo, "Config reloaded", slog.String("changed", strings.Join(changed, ", ")))
+		w.metrics.ConfigReloads.WithLabelValues("applied").Inc()
+	}
+}
+
+func fileSum(pth string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(pth)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("read config file: %w", err)
+	}
+	return sha256.Sum256(data), nil
+}
@@ -0,0 +1,109 @@
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// File is an append only file which is rotated when it grows over max size:
+// path is renamed to path.1, path.1 to path.2 and so on, files older than max backups are removed
+type File struct {
+	path       string
+	maxSize    int64 // 0 means file is never rotated
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func Open(path string, maxSize int64, maxBackups int) (*File, error) {
+	f := &File{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", f.path, err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat %s: %w", f.path, err)
+	}
+	f.file, f.size = file, stat.Size()
+	return nil
+}
+
+// Write appends p to the file, the file is rotated before p if p doesn't fit, so records aren't split
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", f.path, err)
+	}
+	f.file = nil
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", f.path, err)
+		}
+		return f.open()
+	}
+	if err := os.Remove(Backup(f.path, f.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove oldest backup: %w", err)
+	}
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(Backup(f.path, i), Backup(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("shift backup: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, Backup(f.path, 1)); err != nil {
+		return fmt.Errorf("rename %s: %w", f.path, err)
+	}
+	return f.open()
+}
+
+// Sync flushes written data to disk
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Backup returns path of i-th backup of the file, the 1st one is the newest
+func Backup(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
@@ -0,0 +1,157 @@
+package ticker
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Ticker = &FakeTicker{}
+
+// FakeTicker is a manual clock: its tickers and timers fire only when time is moved with Advance.
+// Channels are buffered by one and ticks are dropped if nobody reads them, same as time.Ticker does.
+type FakeTicker struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+	created int
+}
+
+type fakeTimer struct {
+	ch     chan time.Time
+	next   time.Time
+	period time.Duration // 0 for timers
+}
+
+func NewFakeTicker(now time.Time) *FakeTicker {
+	t := &FakeTicker{now: now}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+func (t *FakeTicker) GetTicker(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		panic("non-positive interval for FakeTicker.GetTicker")
+	}
+	return t.add(d, d)
+}
+
+func (t *FakeTicker) GetTimer(d time.Duration) (<-chan time.Time, func()) {
+	return t.add(d, 0)
+}
+
+func (t *FakeTicker) add(d, period time.Duration) (<-chan time.Time, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tmr := &fakeTimer{
+		ch:     make(chan time.Time, 1),
+		next:   t.now.Add(d),
+		period: period,
+	}
+	t.timers = append(t.timers, tmr)
+	t.created++
+	t.cond.Broadcast()
+	return tmr.ch, func() { t.remove(tmr) }
+}
+
+func (t *FakeTicker) remove(tmr *fakeTimer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, cur := range t.timers {
+		if cur == tmr {
+			t.timers = append(t.timers[:i], t.timers[i+1:]...)
+			t.cond.Broadcast()
+			return
+		}
+	}
+}
+
+// Now returns current fake time
+func (t *FakeTicker) Now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now
+}
+
+// Advance moves fake time forward firing due tickers and timers in chronological order
+func (t *FakeTicker) Advance(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	end := t.now.Add(d)
+	for {
+		var first *fakeTimer
+		for _, tmr := range t.timers {
+			if !tmr.next.After(end) && (first == nil || tmr.next.Before(first.next)) {
+				first = tmr
+			}
+		}
+		if first == nil {
+			break
+		}
+
+		t.now = first.next
+		select {
+		case first.ch <- t.now:
+		default:
+		}
+		if first.period > 0 {
+			first.next = first.next.Add(first.period)
+		} else {
+			for i, cur := range t.timers {
+				if cur == first {
+					t.timers = append(t.timers[:i], t.timers[i+1:]...)
+					break
+				}
+			}
+			t.cond.Broadcast()
+		}
+	}
+	t.now = end
+}
+
+// Active returns amount of tickers and timers that are neither stopped nor fired
+func (t *FakeTicker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.timers)
+}
+
+// BlockUntil waits until at least n tickers and timers are active,
+// so test can be sure that code under test reached its select before calling Advance
+func (t *FakeTicker) BlockUntil(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.timers) < n {
+		t.cond.Wait()
+	}
+}
+
+// BlockUntilCreated waits until tickers and timers were created at least n times in total. Unlike BlockUntil
+// it notices code that replaces a ticker with a timer, as amount of active ones stays the same then.
+func (t *FakeTicker) BlockUntilCreated(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.created < n {
+		t.cond.Wait()
+	}
+}
+
+// BlockUntilRead waits until ticks of active tickers are read, so test can be sure that code under test
+// got the tick before the next Advance, otherwise the next tick would be dropped
+func (t *FakeTicker) BlockUntilRead() {
+	for {
+		t.mu.Lock()
+		unread := false
+		for _, tmr := range t.timers {
+			unread = unread || len(tmr.ch) > 0
+		}
+		t.mu.Unlock()
+		if !unread {
+			return
+		}
+		time.Sleep(time.Millisecond) // reads of channels can't be waited for with cond
+	}
+}
@@ -0,0 +1,139 @@
+package ticker
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func received(ch <-chan time.Time) (time.Time, bool) {
+	select {
+	case tm := <-ch:
+		return tm, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeTickerAdvance(t *testing.T) {
+	ft := NewFakeTicker(start)
+	tckr, _ := ft.GetTicker(time.Second)
+	tmr, _ := ft.GetTimer(1500 * time.Millisecond)
+
+	ft.Advance(999 * time.Millisecond)
+	if _, ok := received(tckr); ok {
+		t.Fatal("ticker fired before its interval")
+	}
+
+	ft.Advance(time.Millisecond)
+	if tm, ok := received(tckr); !ok || !tm.Equal(start.Add(time.Second)) {
+		t.Fatalf("ticker tick = %v, %v, want %v", tm, ok, start.Add(time.Second))
+	}
+
+	ft.Advance(time.Second)
+	if tm, ok := received(tmr); !ok || !tm.Equal(start.Add(1500*time.Millisecond)) {
+		t.Fatalf("timer fire = %v, %v, want %v", tm, ok, start.Add(1500*time.Millisecond))
+	}
+	if tm, ok := received(tckr); !ok || !tm.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("ticker tick = %v, %v, want %v", tm, ok, start.Add(2*time.Second))
+	}
+	if got := ft.Now(); !got.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("Now() = %v, want %v", got, start.Add(2*time.Second))
+	}
+	if got := ft.Active(); got != 1 {
+		t.Fatalf("Active() = %d after timer fired, want 1", got)
+	}
+
+	ft.Advance(time.Minute)
+	if _, ok := received(tmr); ok {
+		t.Fatal("timer fired twice")
+	}
+}
+
+func TestFakeTickerDropsUnreadTicks(t *testing.T) {
+	ft := NewFakeTicker(start)
+	tckr, _ := ft.GetTicker(time.Second)
+
+	ft.Advance(5 * time.Second)
+	if tm, ok := received(tckr); !ok || !tm.Equal(start.Add(time.Second)) {
+		t.Fatalf("buffered tick = %v, %v, want the first one", tm, ok)
+	}
+	if _, ok := received(tckr); ok {
+		t.Fatal("more than one tick is buffered")
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	ft := NewFakeTicker(start)
+	tckr, stTckr := ft.GetTicker(time.Second)
+	tmr, stTmr := ft.GetTimer(time.Second)
+
+	stTckr()
+	stTmr()
+	stTmr() // stop is idempotent
+	if got := ft.Active(); got != 0 {
+		t.Fatalf("Active() = %d after stop, want 0", got)
+	}
+
+	ft.Advance(time.Minute)
+	if _, ok := received(tckr); ok {
+		t.Fatal("stopped ticker fired")
+	}
+	if _, ok := received(tmr); ok {
+		t.Fatal("stopped timer fired")
+	}
+}
+
+func TestFakeTickerBlockUntil(t *testing.T) {
+	ft := NewFakeTicker(start)
+	done := make(chan struct{})
+	go func() {
+		ft.BlockUntil(2)
+		close(done)
+	}()
+
+	ft.GetTimer(time.Second)
+	select {
+	case <-done:
+		t.Fatal("BlockUntil(2) returned with one active timer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	ft.GetTicker(time.Second)
+	<-done
+}
+
+func TestFakeTickerBlockUntilCreated(t *testing.T) {
+	ft := NewFakeTicker(start)
+	_, stTckr := ft.GetTicker(time.Second)
+	done := make(chan struct{})
+	go func() {
+		ft.BlockUntilCreated(2)
+		close(done)
+	}()
+
+	stTckr()
+	ft.GetTimer(time.Second) // the same amount of active timers
+	<-done
+}
+
+func TestFakeTickerBlockUntilRead(t *testing.T) {
+	ft := NewFakeTicker(start)
+	tckr, _ := ft.GetTicker(time.Second)
+	ft.BlockUntilRead() // nothing to read
+
+	ticks := make(chan time.Time)
+	go func() {
+		for range 3 {
+			ticks <- <-tckr
+		}
+	}()
+	for i := 1; i <= 3; i++ {
+		ft.Advance(time.Second)
+		ft.BlockUntilRead()
+		if tm := <-ticks; !tm.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("tick %d = %v, none must be dropped", i, tm)
+		}
+	}
+}
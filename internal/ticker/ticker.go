@@ -8,7 +8,7 @@ import (
 
 type Ticker interface {
 	GetTicker(time.Duration) (ch <-chan time.Time, stop func())
-	GetTimer(time.Duration) <-chan time.Time
+	GetTimer(time.Duration) (ch <-chan time.Time, stop func())
 }
 
 type BaseTicker struct{}
@@ -18,8 +18,9 @@ func (t *BaseTicker) GetTicker(d time.Duration) (<-chan time.Time, func()) {
 	return tckr.C, func() { tckr.Stop() }
 }
 
-func (t *BaseTicker) GetTimer(d time.Duration) <-chan time.Time {
-	return time.NewTimer(d).C
+func (t *BaseTicker) GetTimer(d time.Duration) (<-chan time.Time, func()) {
+	tmr := time.NewTimer(d)
+	return tmr.C, func() { tmr.Stop() }
 }
 
 func GetTicker() Ticker {
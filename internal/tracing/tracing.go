@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Name is the instrumentation name of tracers created by the app
+const Name = "github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election"
+
+// Options configure where spans are exported
+type Options struct {
+	Exporter string
+	File     string // used by file exporter
+	NodeID   string
+}
+
+// New creates tracer provider exporting spans as json lines, returned shutdown flushes buffered spans
+func New(opts Options) (trace.TracerProvider, func(context.Context) error, error) {
+	var out io.Writer
+	closeOut := func() error { return nil }
+	switch opts.Exporter {
+	case ExporterNone:
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		out = os.Stdout
+	case ExporterFile:
+		file, err := os.OpenFile(opts.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open trace file: %w", err)
+		}
+		out, closeOut = file, file.Close
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		closeOut()
+		return nil, nil, fmt.Errorf("create exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "election"),
+			attribute.String("service.instance.id", opts.NodeID),
+		)),
+	)
+	return provider, func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cerr := closeOut(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns tracer of the provider which started span in ctx, it's noop if there is no span
+func Tracer(ctx context.Context) trace.Tracer {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(Name)
+}
+
+// Scope holds context of the running state span. Backend calls don't get context,
+// so traced connections take parent span from the scope.
+type Scope struct {
+	ctx atomic.Pointer[context.Context]
+}
+
+func (s *Scope) Set(ctx context.Context) {
+	s.ctx.Store(&ctx)
+}
+
+// Context returns the last set context, background if there was none
+func (s *Scope) Context() context.Context {
+	if ctx := s.ctx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
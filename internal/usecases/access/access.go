@@ -0,0 +1,114 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/go-zookeeper/zk"
+)
+
+// ErrNoPermission is returned when node identity lacks permissions the election needs
+var ErrNoPermission = errors.New("no permission")
+
+var permNames = []struct {
+	perm int32
+	name string
+}{
+	{zk.PermRead, "read"},
+	{zk.PermWrite, "write"},
+	{zk.PermCreate, "create"},
+	{zk.PermDelete, "delete"},
+	{zk.PermAdmin, "admin"},
+}
+
+// Verify checks ACLs of election paths against node identity: world entries and digest entry of zk-auth credentials.
+// Entries of other schemes, like ip, can't be checked by client and are assumed to match.
+// Absent path needs create permission on its nearest existing ancestor.
+func Verify(conn backend.Conn, opts cmdargs.RunArgs) error {
+	creds, err := opts.ZkCredentials()
+	if err != nil {
+		return err
+	}
+	v := verifier{conn: conn}
+	if user, password, ok := strings.Cut(creds, ":"); ok {
+		v.digestID = zk.DigestACL(zk.PermAll, user, password)[0].ID
+	}
+
+	perms := needs(opts)
+	paths := make([]string, 0, len(perms))
+	for pth := range perms {
+		paths = append(paths, pth)
+	}
+	sort.Strings(paths)
+	var errs []error
+	for _, pth := range paths {
+		errs = append(errs, v.need(pth, perms[pth]))
+	}
+	return errors.Join(errs...)
+}
+
+// needs returns permissions node needs on election paths, observer only reads them
+func needs(opts cmdargs.RunArgs) map[string]int32 {
+	if opts.Observer {
+		return map[string]int32{
+			path.Dir(opts.ElectionFileDir): zk.PermRead,
+			opts.LeaderFileDir:             zk.PermRead,
+		}
+	}
+	dirPerms := int32(zk.PermRead | zk.PermCreate | zk.PermDelete)
+	res := map[string]int32{
+		path.Dir(opts.ElectionFileDir): zk.PermCreate | zk.PermDelete,
+		opts.LeaderFileDir:             dirPerms,
+		opts.MembersDir:                dirPerms,
+	}
+	if opts.Partitions > 0 {
+		res[opts.PartitionsDir] = dirPerms
+	}
+	return res
+}
+
+type verifier struct {
+	conn     backend.Conn
+	digestID string // empty if session isn't authenticated
+}
+
+func (v verifier) need(pth string, perms int32) error {
+	acl, _, err := v.conn.GetACL(pth)
+	switch {
+	case errors.Is(err, zk.ErrNoNode) && pth != "/":
+		return v.need(path.Dir(pth), zk.PermCreate)
+	case errors.Is(err, zk.ErrNoAuth):
+		return fmt.Errorf("%s: %w to read acl", pth, ErrNoPermission)
+	case err != nil:
+		return fmt.Errorf("get acl of %s: %w", pth, err)
+	}
+
+	var granted int32
+	for _, a := range acl {
+		switch {
+		case a.Scheme == "world" && a.ID == "anyone", a.Scheme == "digest" && a.ID == v.digestID:
+			granted |= a.Perms
+		case a.Scheme != "world" && a.Scheme != "digest":
+			granted |= a.Perms
+		}
+	}
+	if missing := perms &^ granted; missing != 0 {
+		return fmt.Errorf("%s: %w to %s", pth, ErrNoPermission, names(missing))
+	}
+	return nil
+}
+
+func names(perms int32) string {
+	var res []string
+	for _, p := range permNames {
+		if perms&p.perm != 0 {
+			res = append(res, p.name)
+		}
+	}
+	return strings.Join(res, ", ")
+}
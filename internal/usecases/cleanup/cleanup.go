@@ -0,0 +1,111 @@
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/go-zookeeper/zk"
+)
+
+// ErrLiveSession is returned when plan touches nodes of a live leader without force
+var ErrLiveSession = errors.New("node belongs to a live leader")
+
+// Target is a node to delete together with its subtree
+type Target struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	// Live is set for ephemeral election node and leader files while its session is alive:
+	// leader may run with other storage capacity and fails if its files disappear
+	Live    bool  `json:"live,omitempty"`
+	version int32 // node is deleted only if it wasn't changed since plan was made
+}
+
+// Plan finds nodes to delete. By default these are persistent election node nobody will ever remove,
+// leader dir children which leader can't own with current storage capacity and leader files with children.
+// With all set everything is planned: election node and every leader file.
+// While election node is ephemeral its session is alive, so all planned nodes are live.
+func Plan(conn backend.Conn, opts cmdargs.RunArgs, all bool) ([]Target, error) {
+	var res []Target
+
+	ok, stat, err := conn.Exists(opts.ElectionFileDir)
+	if err != nil {
+		return nil, fmt.Errorf("stat election node: %w", err)
+	}
+	live := ok && stat.EphemeralOwner != 0
+	if ok {
+		switch {
+		case stat.EphemeralOwner == 0:
+			res = append(res, Target{Path: opts.ElectionFileDir, Reason: "persistent election node, nobody can become leader", version: stat.Version})
+		case all:
+			res = append(res, Target{Path: opts.ElectionFileDir, Reason: fmt.Sprintf("election node of session 0x%x", stat.EphemeralOwner), Live: true, version: stat.Version})
+		}
+	}
+
+	chld, _, err := conn.Children(opts.LeaderFileDir)
+	if errors.Is(err, zk.ErrNoNode) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get leader dir children: %w", err)
+	}
+	for _, name := range chld {
+		pth := opts.LeaderFileDir + "/" + name
+		ok, stat, err := conn.Exists(pth)
+		if err != nil {
+			return nil, fmt.Errorf("stat leader file %s: %w", name, err)
+		}
+		if !ok { // deleted by leader while we were reading
+			continue
+		}
+		var reason string
+		i, err := strconv.Atoi(name)
+		switch {
+		case err != nil || i < 0 || strconv.Itoa(i) != name:
+			reason = "foreign node in leader dir"
+		case i >= opts.StorageCapacity:
+			reason = fmt.Sprintf("leader file out of storage capacity %d", opts.StorageCapacity)
+		case stat.NumChildren != 0:
+			reason = "leader file has children, leader can't rewrite it"
+		case all:
+			reason = "leader file"
+		default:
+			continue
+		}
+		res = append(res, Target{Path: pth, Reason: reason, Live: live, version: stat.Version})
+	}
+	return res, nil
+}
+
+// Apply deletes planned nodes, live ones are deleted only if forced.
+// Nodes that are already absent are skipped, changed since planning ones make Apply fail.
+func Apply(conn backend.Conn, targets []Target, force bool) error {
+	if !force {
+		for _, t := range targets {
+			if t.Live {
+				return fmt.Errorf("%s: %w", t.Path, ErrLiveSession)
+			}
+		}
+	}
+	for _, t := range targets {
+		if err := deleteTree(conn, t.Path, t.version); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return fmt.Errorf("delete %s: %w", t.Path, err)
+		}
+	}
+	return nil
+}
+
+func deleteTree(conn backend.Conn, pth string, version int32) error {
+	chld, _, err := conn.Children(pth)
+	if err != nil {
+		return err
+	}
+	for _, ch := range chld {
+		if err := deleteTree(conn, pth+"/"+ch, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return err
+		}
+	}
+	return conn.Delete(pth, version)
+}
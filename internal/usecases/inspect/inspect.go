@@ -0,0 +1,168 @@
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/go-zookeeper/zk"
+)
+
+// LeaderInfo describes election node of the current leader
+type LeaderInfo struct {
+	Present     bool             `json:"present"`
+	Identity    *states.Identity `json:"identity,omitempty"` // nil if leader wrote no identity
+	SessionID   int64            `json:"session_id,omitempty"`
+	Created     time.Time        `json:"created,omitempty"`
+	IsEphemeral bool             `json:"is_ephemeral"`
+}
+
+// RingFile is one of the files leader writes to its dir
+type RingFile struct {
+	Name       string    `json:"name"`
+	Created    time.Time `json:"created"`
+	AgeSeconds float64   `json:"age_seconds"`
+	Children   int32     `json:"children,omitempty"`
+}
+
+// Leader reads election node, absent node is not an error
+func Leader(conn backend.Conn, electionPath string) (LeaderInfo, error) {
+	data, stat, err := conn.Get(electionPath)
+	if errors.Is(err, zk.ErrNoNode) {
+		return LeaderInfo{}, nil
+	}
+	if err != nil {
+		return LeaderInfo{}, fmt.Errorf("get election node: %w", err)
+	}
+
+	info := LeaderInfo{
+		Present:     true,
+		SessionID:   stat.EphemeralOwner,
+		Created:     time.UnixMilli(stat.Ctime),
+		IsEphemeral: stat.EphemeralOwner != 0,
+	}
+	if id, ok := states.ParseIdentity(data); ok {
+		info.Identity = &id
+	}
+	return info, nil
+}
+
+// Ring reads leader files ordered by creation, absent dir is not an error
+func Ring(conn backend.Conn, dir string, now time.Time) ([]RingFile, error) {
+	chld, _, err := conn.Children(dir)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get leader dir children: %w", err)
+	}
+
+	files := make([]RingFile, 0, len(chld))
+	for _, name := range chld {
+		ok, stat, err := conn.Exists(dir + "/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("stat leader file %s: %w", name, err)
+		}
+		if !ok { // deleted by leader while we were reading
+			continue
+		}
+		created := time.UnixMilli(stat.Ctime)
+		files = append(files, RingFile{Name: name, Created: created, AgeSeconds: now.Sub(created).Seconds(), Children: stat.NumChildren})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].Created.Equal(files[j].Created) {
+			return files[i].Created.Before(files[j].Created)
+		}
+		return ringIndex(files[i].Name) < ringIndex(files[j].Name)
+	})
+	return files, nil
+}
+
+func ringIndex(name string) int {
+	i, err := strconv.Atoi(name)
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// Report is the election state as seen in zookeeper
+type Report struct {
+	Namespace    string     `json:"namespace,omitempty"`
+	ElectionPath string     `json:"election_path"`
+	StoragePath  string     `json:"storage_path"`
+	Leader       LeaderInfo `json:"leader"`
+	Storage      []RingFile `json:"storage"`
+	Anomalies    []string   `json:"anomalies"`
+}
+
+// Inspect reads election and leader dir nodes and looks for anomalies in them
+func Inspect(conn backend.Conn, opts cmdargs.RunArgs, now time.Time) (Report, error) {
+	leader, err := Leader(conn, opts.ElectionFileDir)
+	if err != nil {
+		return Report{}, err
+	}
+	ring, err := Ring(conn, opts.LeaderFileDir, now)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Report{
+		Namespace:    opts.Namespace,
+		ElectionPath: opts.ElectionFileDir,
+		StoragePath:  opts.LeaderFileDir,
+		Leader:       leader,
+		Storage:      ring,
+		Anomalies:    anomalies(leader, ring, opts, now),
+	}, nil
+}
+
+// staleFactor is how many leader timeouts may pass without new leader file before it's reported
+const staleFactor = 3
+
+func anomalies(leader LeaderInfo, ring []RingFile, opts cmdargs.RunArgs, now time.Time) []string {
+	res := []string{}
+	switch {
+	case !leader.Present:
+		res = append(res, "no leader: election node is absent")
+	case !leader.IsEphemeral:
+		res = append(res, "election node is persistent, nobody can become leader until it is deleted")
+	case leader.Identity == nil:
+		res = append(res, "leader wrote no identity to election node")
+	}
+
+	if len(ring) > opts.StorageCapacity {
+		res = append(res, fmt.Sprintf("leader dir has %d files, more than storage capacity %d", len(ring), opts.StorageCapacity))
+	}
+	seen := map[int]bool{}
+	for _, f := range ring {
+		i := ringIndex(f.Name)
+		if i < 0 || i >= opts.StorageCapacity {
+			res = append(res, fmt.Sprintf("foreign node %q in leader dir", f.Name))
+			continue
+		}
+		if f.Children != 0 {
+			res = append(res, fmt.Sprintf("leader file %q has children", f.Name))
+		}
+		seen[i] = true
+	}
+	for i := range len(seen) {
+		if !seen[i] {
+			res = append(res, fmt.Sprintf("leader files are not numbered contiguously, %d is missing", i))
+			break
+		}
+	}
+
+	if leader.Present && len(ring) != 0 {
+		newest := ring[len(ring)-1]
+		if age := now.Sub(newest.Created); age > staleFactor*opts.LeaderTimeout {
+			res = append(res, fmt.Sprintf("newest leader file is %s old, leader writes every %s", age.Round(time.Millisecond), opts.LeaderTimeout))
+		}
+	}
+	return res
+}
@@ -0,0 +1,141 @@
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/go-zookeeper/zk"
+)
+
+// ErrDuplicateNode is returned when member node of this node id is held by another session
+var ErrDuplicateNode = errors.New("member node is held by another session")
+
+// States reported by members, they are names of attemper_s and leader_s states
+const (
+	StateAttemper = "AttemperState"
+	StateLeader   = "LeaderState"
+)
+
+// staleHeartbeats is how many heartbeat intervals member may miss before it's considered stale
+const staleHeartbeats = 3
+
+// Member is written to ephemeral member node, node name is the node id
+type Member struct {
+	NodeID    string    `json:"node_id"`
+	AdminAddr string    `json:"admin_addr,omitempty"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"` // start of the current state
+	Priority  int       `json:"priority"`
+	// Eligible is false while node is paused or holds off contesting after resign
+	Eligible bool `json:"eligible"`
+	// Handover is set by candidate which asks leader of lower priority to give leadership up
+	Handover  bool      `json:"handover,omitempty"`
+	Joined    time.Time `json:"joined"`
+	Heartbeat time.Time `json:"heartbeat"`
+	// HeartbeatInterval is how often member rewrites its node, readers judge staleness by it
+	HeartbeatInterval time.Duration `json:"heartbeat_interval,omitempty"`
+	SessionID         int64         `json:"session_id,omitempty"` // filled from node stat on List
+}
+
+func (m Member) Marshal() []byte {
+	data, _ := json.Marshal(m) // struct of plain fields can't fail to marshal
+	return data
+}
+
+// Stale reports whether member missed several heartbeats, its process is likely hung
+// as zookeeper would remove the node of dead session. Interval of the member is used,
+// fallback one is for members which didn't write it.
+func (m Member) Stale(now time.Time, fallback time.Duration) bool {
+	interval := m.HeartbeatInterval
+	if interval <= 0 {
+		interval = fallback
+	}
+	return now.Sub(m.Heartbeat) > staleHeartbeats*interval
+}
+
+// Outranking returns fresh eligible candidate of the highest priority above the given one,
+// node defers to it instead of becoming leader. Nil means there is no such candidate.
+func Outranking(members []Member, self string, priority int, now time.Time, interval time.Duration) *Member {
+	var best *Member
+	for i, m := range members {
+		if m.NodeID == self || m.State != StateAttemper || !m.Eligible || m.Stale(now, interval) || m.Priority <= priority {
+			continue
+		}
+		if best == nil || m.Priority > best.Priority {
+			best = &members[i]
+		}
+	}
+	return best
+}
+
+// Register creates ephemeral member node and its parent dir if needed.
+// Node left by this session is overwritten, node of another session is ErrDuplicateNode.
+func Register(conn backend.Conn, dir string, m Member, acl []zk.ACL) error {
+	if _, err := conn.Create(dir, []byte{}, 0, acl); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+		return fmt.Errorf("create members dir: %w", err)
+	}
+	pth := dir + "/" + m.NodeID
+	_, err := conn.Create(pth, m.Marshal(), zk.FlagEphemeral, acl)
+	if !errors.Is(err, zk.ErrNodeExists) {
+		return err
+	}
+	ok, stat, err := conn.Exists(pth)
+	if err != nil {
+		return fmt.Errorf("stat member node: %w", err)
+	}
+	if ok && stat.EphemeralOwner != conn.SessionID() {
+		return fmt.Errorf("%s: %w", m.NodeID, ErrDuplicateNode)
+	}
+	_, err = conn.Set(pth, m.Marshal(), -1)
+	return err
+}
+
+// Unregister deletes member node if it's held by this session
+func Unregister(conn backend.Conn, dir, nodeID string) error {
+	pth := dir + "/" + nodeID
+	ok, stat, err := conn.Exists(pth)
+	if err != nil {
+		return fmt.Errorf("stat member node: %w", err)
+	}
+	if !ok || stat.EphemeralOwner != conn.SessionID() {
+		return nil
+	}
+	if err := conn.Delete(pth, stat.Version); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("delete member node: %w", err)
+	}
+	return nil
+}
+
+// List reads members ordered by node id, absent dir means no members
+func List(conn backend.Conn, dir string) ([]Member, error) {
+	chld, _, err := conn.Children(dir)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get members: %w", err)
+	}
+	sort.Strings(chld)
+
+	res := make([]Member, 0, len(chld))
+	for _, name := range chld {
+		data, stat, err := conn.Get(dir + "/" + name)
+		if errors.Is(err, zk.ErrNoNode) { // left while we were reading
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get member %s: %w", name, err)
+		}
+		var m Member
+		if json.Unmarshal(data, &m) != nil || m.NodeID == "" {
+			m = Member{NodeID: name}
+		}
+		m.SessionID = stat.EphemeralOwner
+		res = append(res, m)
+	}
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package membership
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func TestStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		fallback time.Duration
+		age      time.Duration
+		stale    bool
+	}{
+		{"fresh", time.Second, time.Second, 2 * time.Second, false},
+		{"missed three heartbeats", time.Second, time.Second, 3 * time.Second, false},
+		{"missed more than three heartbeats", time.Second, time.Second, 3*time.Second + 1, true},
+		{"writer interval is longer than reader one", 5 * time.Second, time.Second, 10 * time.Second, false},
+		{"writer interval is shorter than reader one", time.Second, 5 * time.Second, 10 * time.Second, true},
+		{"writer without interval", 0, 5 * time.Second, 10 * time.Second, false},
+		{"writer without interval missed heartbeats", 0, time.Second, 10 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Member{Heartbeat: start, HeartbeatInterval: tt.interval}
+			if got := m.Stale(start.Add(tt.age), tt.fallback); got != tt.stale {
+				t.Fatalf("Stale = %t, want %t", got, tt.stale)
+			}
+		})
+	}
+}
+
+func TestOutranking(t *testing.T) {
+	candidate := func(id string, priority int) Member {
+		return Member{NodeID: id, State: StateAttemper, Priority: priority, Eligible: true, Heartbeat: start, HeartbeatInterval: time.Second}
+	}
+	paused := candidate("paused", 9)
+	paused.Eligible = false
+	leader := candidate("leader", 9)
+	leader.State = StateLeader
+	stale := candidate("stale", 9)
+	stale.Heartbeat = start.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		members []Member
+		want    string
+	}{
+		{"no members", nil, ""},
+		{"only self", []Member{candidate("self", 5)}, ""},
+		{"lower and equal priority", []Member{candidate("low", 1), candidate("equal", 2)}, ""},
+		{"higher priority", []Member{candidate("low", 1), candidate("high", 3)}, "high"},
+		{"the highest priority", []Member{candidate("high", 3), candidate("highest", 7), candidate("higher", 5)}, "highest"},
+		{"the first of equal priorities", []Member{candidate("b", 3), candidate("a", 3)}, "b"},
+		{"paused, leader and stale are skipped", []Member{paused, leader, stale, candidate("high", 3)}, "high"},
+		{"self of higher priority is skipped", []Member{candidate("self", 9)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Outranking(tt.members, "self", 2, start.Add(time.Second), time.Second)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Fatalf("outranked by %s, want nobody", got.NodeID)
+			case tt.want != "" && (got == nil || got.NodeID != tt.want):
+				t.Fatalf("outranked by %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package partition
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// Assign distributes partitions among members with rendezvous hashing: partition goes to the member
+// with the highest hash of member and partition, so member leaving moves only its own partitions
+func Assign(members []string, partitions int) map[int]string {
+	res := make(map[int]string, partitions)
+	if len(members) == 0 {
+		return res
+	}
+	for p := range partitions {
+		var best string
+		var bestScore uint64
+		for _, m := range members {
+			if score := weight(m, p); best == "" || score > bestScore || (score == bestScore && m < best) {
+				best, bestScore = m, score
+			}
+		}
+		res[p] = best
+	}
+	return res
+}
+
+func weight(member string, partition int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(partition)))
+	return mix(h.Sum64())
+}
+
+// mix is murmur3 finalizer, fnv alone leaves high bits depending mostly on the member name
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
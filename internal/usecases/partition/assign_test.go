@@ -0,0 +1,75 @@
+package partition
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func members(n int) []string {
+	res := make([]string, 0, n)
+	for i := range n {
+		res = append(res, fmt.Sprintf("node-%d", i))
+	}
+	return res
+}
+
+func TestAssignDeterministic(t *testing.T) {
+	got := Assign([]string{"a", "b", "c"}, 32)
+	if len(got) != 32 {
+		t.Fatalf("assigned %d partitions, want 32", len(got))
+	}
+	// every node computes assignment by itself, so it must not depend on the order of members
+	if other := Assign([]string{"c", "a", "b"}, 32); !reflect.DeepEqual(got, other) {
+		t.Fatalf("assignment depends on members order:\n%v\n%v", got, other)
+	}
+	if len(Assign(nil, 32)) != 0 {
+		t.Fatal("partitions are assigned without members")
+	}
+}
+
+func TestAssignSpread(t *testing.T) {
+	counts := map[string]int{}
+	for _, m := range Assign(members(4), 256) {
+		counts[m]++
+	}
+	for _, m := range members(4) {
+		if counts[m] < 32 { // a half of fair share
+			t.Errorf("%s got %d of 256 partitions", m, counts[m])
+		}
+	}
+}
+
+func TestAssignMemberLeaves(t *testing.T) {
+	all := members(5)
+	before := Assign(all, 64)
+	left := "node-2"
+	after := Assign(append(all[:2:2], all[3:]...), 64)
+
+	moved := 0
+	for p, m := range before {
+		if m != left {
+			if after[p] != m {
+				t.Errorf("partition %d moved from %s to %s though its member stayed", p, m, after[p])
+			}
+			continue
+		}
+		moved++
+		if after[p] == left {
+			t.Errorf("partition %d stayed with the left member", p)
+		}
+	}
+	if moved == 0 {
+		t.Fatal("left member had no partitions, test checks nothing")
+	}
+}
+
+func TestAssignMemberJoins(t *testing.T) {
+	before := Assign(members(4), 64)
+	after := Assign(members(5), 64)
+	for p, m := range after {
+		if m != before[p] && m != "node-4" {
+			t.Errorf("partition %d moved from %s to %s, only joined member may take partitions", p, before[p], m)
+		}
+	}
+}
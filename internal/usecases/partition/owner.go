@@ -0,0 +1,254 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/go-zookeeper/zk"
+)
+
+// StatusSource tells whether node takes part in the election, it's implemented by run.Runner
+type StatusSource interface {
+	Status() run.Status
+}
+
+// Status is a snapshot of partitions owned by the node
+type Status struct {
+	Partitions int      `json:"partitions"`
+	Members    []string `json:"members"`
+	Owned      []int    `json:"owned"`
+	// Pending are assigned to the node but still held by their previous owner
+	Pending []int `json:"pending,omitempty"`
+}
+
+// Owner takes partitions assigned to the node while it's attemper or leader. Members are the nodes
+// registered in members dir, partition is owned by ephemeral node partitions-dir/<partition>.
+// Assignment is checked every attempter timeout and on every change of members.
+func NewOwner(logger *slog.Logger, connector backend.Connector, source StatusSource, metrics *metrics.Metrics, ticker ticker.Ticker, opts *cmdargs.Live) *Owner {
+	return &Owner{
+		logger:    logger.With("subsystem", "PartitionOwner"),
+		connector: connector,
+		source:    source,
+		metrics:   metrics,
+		ticker:    ticker,
+		options:   opts,
+		owned:     map[int]bool{},
+		status:    Status{Partitions: opts.Get().Partitions, Owned: []int{}},
+	}
+}
+
+type Owner struct {
+	logger    *slog.Logger
+	connector backend.Connector
+	source    StatusSource
+	metrics   *metrics.Metrics
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
+
+	session   int64 // session owned partitions belong to
+	owned     map[int]bool
+	assigned  []int
+	membersCh <-chan zk.Event
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func (o *Owner) Status() Status {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.status
+}
+
+// Run rebalances partitions until ctx is done, then releases them
+func (o *Owner) Run(ctx context.Context) error {
+	tckr, stTckr := o.ticker.GetTicker(o.options.Get().AttempterTimeout)
+	defer stTckr()
+	for {
+		o.step(ctx)
+		select {
+		case <-tckr:
+		case <-o.membersCh:
+			o.membersCh = nil
+		case <-ctx.Done():
+			if conn := o.connector.Current(); conn != nil && conn.SessionID() == o.session {
+				o.leave(ctx, conn)
+			}
+			return nil
+		}
+	}
+}
+
+func (o *Owner) step(ctx context.Context) {
+	conn := o.connector.Current()
+	if conn == nil || conn.State() != zk.StateHasSession {
+		return
+	}
+	if sid := conn.SessionID(); sid != o.session { // ephemeral nodes of the old session are gone with it
+		o.session = sid
+		o.owned = map[int]bool{}
+		o.membersCh = nil
+	}
+	if !o.source.Status().Contending {
+		o.leave(ctx, conn)
+		return
+	}
+	if err := o.rebalance(ctx, conn); err != nil {
+		o.logger.LogAttrs(ctx, slog.LevelError, "Failed to rebalance partitions", slog.String("error", err.Error()))
+	}
+}
+
+func (o *Owner) rebalance(ctx context.Context, conn backend.Conn) error {
+	opts := o.options.Get()
+	var members []string
+	var err error
+	if o.membersCh == nil {
+		members, _, o.membersCh, err = conn.ChildrenW(opts.MembersDir)
+	} else {
+		members, _, err = conn.Children(opts.MembersDir)
+	}
+	if errors.Is(err, zk.ErrNoNode) { // no member registered yet, dir is created by registrar
+		o.membersCh = nil
+		return nil
+	}
+	if err != nil {
+		o.membersCh = nil
+		return fmt.Errorf("get members: %w", err)
+	}
+	sort.Strings(members)
+
+	var assigned []int
+	for p, member := range Assign(members, opts.Partitions) {
+		if member == opts.NodeID {
+			assigned = append(assigned, p)
+		}
+	}
+	sort.Ints(assigned)
+	if !equal(assigned, o.assigned) {
+		o.metrics.PartitionRebalances.Inc()
+		o.logger.LogAttrs(ctx, slog.LevelInfo, "partitions assigned", slog.Int("members", len(members)), slog.Any("partitions", assigned))
+		o.assigned = assigned
+	}
+
+	mine := make(map[int]bool, len(assigned))
+	for _, p := range assigned {
+		mine[p] = true
+	}
+	var errs []error
+	for p := range o.owned {
+		if !mine[p] {
+			errs = append(errs, o.release(conn, p))
+		}
+	}
+	if _, err := conn.Create(opts.PartitionsDir, []byte{}, 0, backend.ACL(opts.ZkACL)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+		return errors.Join(append(errs, fmt.Errorf("create partitions dir: %w", err))...)
+	}
+	var pending []int
+	for _, p := range assigned {
+		if o.owned[p] {
+			continue
+		}
+		ok, err := o.acquire(conn, p)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !ok {
+			pending = append(pending, p)
+		}
+	}
+	o.setStatus(opts.Partitions, members, pending)
+	return errors.Join(errs...)
+}
+
+// acquire creates partition node, it's not an error if previous owner still holds it
+func (o *Owner) acquire(conn backend.Conn, p int) (bool, error) {
+	pth := o.path(p)
+	opts := o.options.Get()
+	_, err := conn.Create(pth, []byte(opts.NodeID), zk.FlagEphemeral, backend.ACL(opts.ZkACL))
+	if errors.Is(err, zk.ErrNodeExists) {
+		ok, stat, err := conn.Exists(pth)
+		if err != nil {
+			return false, fmt.Errorf("stat partition %d: %w", p, err)
+		}
+		if !ok || stat.EphemeralOwner != conn.SessionID() {
+			return false, nil
+		}
+	} else if err != nil {
+		return false, fmt.Errorf("create partition %d: %w", p, err)
+	}
+	o.owned[p] = true
+	return true, nil
+}
+
+// release deletes partition node if it's still owned by node's session
+func (o *Owner) release(conn backend.Conn, p int) error {
+	delete(o.owned, p)
+	pth := o.path(p)
+	ok, stat, err := conn.Exists(pth)
+	if err != nil {
+		return fmt.Errorf("stat partition %d: %w", p, err)
+	}
+	if !ok || stat.EphemeralOwner != conn.SessionID() {
+		return nil
+	}
+	if err := conn.Delete(pth, stat.Version); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("delete partition %d: %w", p, err)
+	}
+	return nil
+}
+
+// leave releases all partitions, so that other members take them over
+func (o *Owner) leave(ctx context.Context, conn backend.Conn) {
+	opts := o.options.Get()
+	var errs []error
+	for p := range o.owned {
+		errs = append(errs, o.release(conn, p))
+	}
+	if err := errors.Join(errs...); err != nil {
+		o.logger.LogAttrs(ctx, slog.LevelError, "Failed to release partitions", slog.String("error", err.Error()))
+	}
+	if o.assigned != nil {
+		o.logger.LogAttrs(ctx, slog.LevelInfo, "partitions released")
+		o.assigned = nil
+	}
+	o.setStatus(opts.Partitions, nil, nil)
+}
+
+func (o *Owner) setStatus(partitions int, members []string, pending []int) {
+	owned := make([]int, 0, len(o.owned))
+	for p := range o.owned {
+		owned = append(owned, p)
+	}
+	sort.Ints(owned)
+	o.metrics.PartitionsOwned.Set(float64(len(owned)))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.status = Status{Partitions: partitions, Members: members, Owned: owned, Pending: pending}
+}
+
+func (o *Owner) path(p int) string {
+	return o.options.Get().PartitionsDir + "/" + strconv.Itoa(p)
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
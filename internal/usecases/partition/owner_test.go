@@ -0,0 +1,215 @@
+package partition
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/go-zookeeper/zk"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const partitions = 16
+
+type contending struct {
+	on atomic.Bool
+}
+
+func (c *contending) Status() run.Status {
+	return run.Status{Contending: c.on.Load()}
+}
+
+// node is an owner of its own session, its member node is registered by the test
+type node struct {
+	id        string
+	owner     *Owner
+	source    *contending
+	connector *backend.FakeConnector
+}
+
+func newNode(t *testing.T, srv *backend.FakeServer, id string) *node {
+	t.Helper()
+	connector := &backend.FakeConnector{Server: srv}
+	conn, err := connector.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Create("/members/"+id, nil, zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	m := &metrics.Metrics{
+		PartitionsOwned:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "partitions_owned"}),
+		PartitionRebalances: prometheus.NewCounter(prometheus.CounterOpts{Name: "partition_rebalances"}),
+	}
+	opts := cmdargs.NewLive(cmdargs.RunArgs{
+		NodeID:           id,
+		ZkACL:            cmdargs.ACLOpen,
+		AttempterTimeout: time.Second,
+		Partitions:       partitions,
+		PartitionsDir:    "/partitions",
+		MembersDir:       "/members",
+	})
+	n := &node{id: id, source: &contending{}, connector: connector}
+	n.source.on.Store(true)
+	n.owner = NewOwner(slog.New(slog.NewTextHandler(io.Discard, nil)), connector, n.source, m, ticker.NewFakeTicker(time.Now()), opts)
+	return n
+}
+
+func (n *node) step() {
+	n.owner.step(context.Background())
+}
+
+func newCluster(t *testing.T, ids ...string) (*backend.FakeServer, []*node) {
+	t.Helper()
+	srv := backend.NewFakeServer()
+	if _, err := srv.Connect().Create("/members", nil, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	nodes := make([]*node, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, newNode(t, srv, id))
+	}
+	return srv, nodes
+}
+
+// expected returns partitions assigned to member
+func expected(members []string, member string) []int {
+	res := []int{}
+	for p, m := range Assign(members, partitions) {
+		if m == member {
+			res = append(res, p)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// holders returns node id of every partition node by its ephemeral owner
+func holders(t *testing.T, srv *backend.FakeServer, nodes []*node) map[int]string {
+	t.Helper()
+	conn := srv.Connect()
+	defer conn.Close()
+	chld, _, err := conn.Children("/partitions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := map[int]string{}
+	for _, name := range chld {
+		p, _ := strconv.Atoi(name)
+		_, stat, err := conn.Get("/partitions/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range nodes {
+			if n.connector.Current().SessionID() == stat.EphemeralOwner {
+				res[p] = n.id
+			}
+		}
+	}
+	return res
+}
+
+func TestOwnersTakeAssignedPartitions(t *testing.T) {
+	srv, nodes := newCluster(t, "a", "b", "c")
+	for _, n := range nodes {
+		n.step()
+	}
+	ids := []string{"a", "b", "c"}
+	for _, n := range nodes {
+		st := n.owner.Status()
+		if want := expected(ids, n.id); !reflect.DeepEqual(st.Owned, want) || len(st.Pending) != 0 {
+			t.Errorf("%s owns %v pending %v, want %v", n.id, st.Owned, st.Pending, want)
+		}
+	}
+	if got, want := holders(t, srv, nodes), Assign(ids, partitions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("partition nodes are held by %v, want %v", got, want)
+	}
+
+	// nothing changes while members are the same
+	before := srv.Paths()
+	for _, n := range nodes {
+		n.step()
+	}
+	if !reflect.DeepEqual(srv.Paths(), before) {
+		t.Fatal("partition nodes changed without member changes")
+	}
+}
+
+func TestOwnerHandoff(t *testing.T) {
+	srv, nodes := newCluster(t, "a", "b")
+	a, b := nodes[0], nodes[1]
+	a.step()
+	b.step()
+
+	// joined member waits for partitions its peers still hold
+	c := newNode(t, srv, "c")
+	all := append(nodes, c)
+	c.step()
+	taken := expected([]string{"a", "b", "c"}, "c")
+	if len(taken) == 0 {
+		t.Fatal("joined member got no partitions, test checks nothing")
+	}
+	if st := c.owner.Status(); len(st.Owned) != 0 || !reflect.DeepEqual(st.Pending, taken) {
+		t.Fatalf("joined member owns %v pending %v, want all of %v pending", st.Owned, st.Pending, taken)
+	}
+
+	// peers release them on their next step and joined member acquires them on its own
+	a.step()
+	b.step()
+	c.step()
+	if st := c.owner.Status(); !reflect.DeepEqual(st.Owned, taken) || len(st.Pending) != 0 {
+		t.Fatalf("joined member owns %v pending %v, want %v", st.Owned, st.Pending, taken)
+	}
+	if got, want := holders(t, srv, all), Assign([]string{"a", "b", "c"}, partitions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("partition nodes are held by %v, want %v", got, want)
+	}
+
+	// member that stops contending releases partitions at once, only they move back
+	c.source.on.Store(false)
+	c.step()
+	if st := c.owner.Status(); len(st.Owned) != 0 {
+		t.Fatalf("not contending member owns %v", st.Owned)
+	}
+	if err := c.connector.Current().Delete("/members/c", -1); err != nil {
+		t.Fatal(err)
+	}
+	a.step()
+	b.step()
+	if got, want := holders(t, srv, all), Assign([]string{"a", "b"}, partitions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("partition nodes are held by %v, want %v", got, want)
+	}
+}
+
+func TestOwnerSessionExpired(t *testing.T) {
+	srv, nodes := newCluster(t, "a")
+	a := nodes[0]
+	a.step()
+	if st := a.owner.Status(); len(st.Owned) != partitions {
+		t.Fatalf("the only member owns %v", st.Owned)
+	}
+
+	// partitions of expired session are gone with it and are acquired again in the new one
+	a.connector.Current().(*backend.FakeConn).Expire()
+	conn, err := a.connector.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Create("/members/a", nil, zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	a.step()
+	if got := holders(t, srv, nodes); len(got) != partitions {
+		t.Fatalf("new session holds %d partitions, want %d", len(got), partitions)
+	}
+}
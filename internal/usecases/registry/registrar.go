@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/go-zookeeper/zk"
+)
+
+// StatusSource gives current automaton status, it's implemented by run.Runner
+type StatusSource interface {
+	Status() run.Status
+}
+
+// Registrar keeps member node of the node while it's attemper or leader. Node data is rewritten
+// when state, priority or handover request change and every heartbeat interval,
+// member node is deleted when node stops contending.
+func NewRegistrar(logger *slog.Logger, connector backend.Connector, source StatusSource, control *states.Control, ticker ticker.Ticker, opts *cmdargs.Live) *Registrar {
+	return &Registrar{
+		logger:    logger.With("subsystem", "MemberRegistrar"),
+		connector: connector,
+		source:    source,
+		control:   control,
+		ticker:    ticker,
+		options:   opts,
+	}
+}
+
+type Registrar struct {
+	logger    *slog.Logger
+	connector backend.Connector
+	source    StatusSource
+	control   *states.Control
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
+
+	session    int64 // session member node belongs to, 0 if node isn't registered
+	registered membership.Member
+}
+
+// Run keeps member node until ctx is done. State changes are noticed every attempter timeout,
+// node is checked at least every heartbeat interval so that heartbeats aren't late.
+func (r *Registrar) Run(ctx context.Context) error {
+	period := checkPeriod(r.options.Get())
+	tckr, stTckr := r.ticker.GetTicker(period)
+	defer func() { stTckr() }()
+	now := time.Now()
+	for {
+		r.step(ctx, now)
+		select {
+		case now = <-tckr:
+		case <-r.options.Changed():
+			if nPeriod := checkPeriod(r.options.Get()); nPeriod != period {
+				period = nPeriod
+				stTckr()
+				tckr, stTckr = r.ticker.GetTicker(period)
+			}
+			now = time.Now()
+		case <-ctx.Done():
+			if conn := r.connector.Current(); conn != nil && r.session != 0 && conn.SessionID() == r.session {
+				r.leave(ctx, conn)
+			}
+			return nil
+		}
+	}
+}
+
+func checkPeriod(opts cmdargs.RunArgs) time.Duration {
+	return min(opts.AttempterTimeout, opts.HeartbeatInterval)
+}
+
+func (r *Registrar) step(ctx context.Context, now time.Time) {
+	conn := r.connector.Current()
+	if conn == nil || conn.State() != zk.StateHasSession {
+		return
+	}
+	if conn.SessionID() != r.session { // member node of the old session is gone with it
+		r.session = 0
+	}
+	st := r.source.Status()
+	if !st.Contending {
+		if r.session != 0 {
+			r.leave(ctx, conn)
+		}
+		return
+	}
+
+	opts := r.options.Get()
+	m := membership.Member{
+		NodeID:            opts.NodeID,
+		AdminAddr:         opts.AdminAddr,
+		State:             st.State,
+		Since:             st.Since,
+		Priority:          opts.Priority,
+		Eligible:          r.control.CanContest(now),
+		Joined:            now,
+		Heartbeat:         now,
+		HeartbeatInterval: opts.HeartbeatInterval,
+	}
+	// candidate asks leader of lower priority to hand leadership over once it waited long enough
+	m.Handover = opts.HandoverAfter > 0 && m.State == membership.StateAttemper && m.Eligible && now.Sub(m.Since) >= opts.HandoverAfter
+	if r.session == 0 {
+		if err := membership.Register(conn, opts.MembersDir, m, backend.ACL(opts.ZkACL)); err != nil {
+			level := slog.LevelError
+			if errors.Is(err, membership.ErrDuplicateNode) {
+				level = slog.LevelWarn
+			}
+			r.logger.LogAttrs(ctx, level, "Failed to register member", slog.String("error", err.Error()))
+			return
+		}
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "registered as member", slog.String("path", opts.MembersDir+"/"+opts.NodeID), slog.Int("priority", m.Priority))
+		r.session, r.registered = conn.SessionID(), m
+		return
+	}
+	prev := r.registered
+	// heartbeat is written when waiting for the next check would make it late, ticks don't fall
+	// exactly on heartbeat interval as the first heartbeat is written before the first tick
+	due := now.Add(checkPeriod(opts)).Sub(prev.Heartbeat) > opts.HeartbeatInterval
+	if m.State == prev.State && m.Priority == prev.Priority && m.Eligible == prev.Eligible && m.Handover == prev.Handover &&
+		m.HeartbeatInterval == prev.HeartbeatInterval && !due {
+		return
+	}
+	m.Joined = prev.Joined
+	if _, err := conn.Set(opts.MembersDir+"/"+opts.NodeID, m.Marshal(), -1); err != nil {
+		r.logger.LogAttrs(ctx, slog.LevelError, "Failed to update member node", slog.String("error", err.Error()))
+		if errors.Is(err, zk.ErrNoNode) {
+			r.session = 0
+		}
+		return
+	}
+	if m.Handover && !prev.Handover {
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "requested leadership handover", slog.Int("priority", m.Priority))
+	}
+	r.registered = m
+}
+
+func (r *Registrar) leave(ctx context.Context, conn backend.Conn) {
+	opts := r.options.Get()
+	if err := membership.Unregister(conn, opts.MembersDir, opts.NodeID); err != nil {
+		r.logger.LogAttrs(ctx, slog.LevelError, "Failed to unregister member", slog.String("error", err.Error()))
+		return
+	}
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "unregistered as member")
+	r.session = 0
+}
@@ -0,0 +1,214 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+type source struct {
+	mu     sync.Mutex
+	status run.Status
+}
+
+func (s *source) Status() run.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+type registrar struct {
+	srv     *backend.FakeServer
+	ops     <-chan backend.FakeOp
+	clock   *ticker.FakeTicker
+	control *states.Control
+	start   time.Time
+	cancel  context.CancelFunc
+	done    chan error
+}
+
+// runRegistrar starts registrar of attemper node in new session, the first heartbeat is written at once
+func runRegistrar(t *testing.T, args cmdargs.RunArgs) *registrar {
+	t.Helper()
+	srv := backend.NewFakeServer()
+	connector := &backend.FakeConnector{Server: srv}
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// registrar takes the first heartbeat time from the wall clock, fake time mustn't be ahead of it
+	start := time.Now()
+	r := &registrar{
+		srv:     srv,
+		ops:     srv.Record(),
+		clock:   ticker.NewFakeTicker(start),
+		control: states.NewControl(),
+		start:   start,
+		done:    make(chan error, 1),
+	}
+	src := &source{status: run.Status{State: membership.StateAttemper, Since: start, Contending: true}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := NewRegistrar(logger, connector, src, r.control, r.clock, cmdargs.NewLive(args))
+
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	go func() { r.done <- reg.Run(ctx) }()
+	r.waitOp(t, "create", "/members")
+	r.waitOp(t, "create", "/members/node")
+	return r
+}
+
+func (r *registrar) waitOp(t *testing.T, op, pth string) {
+	t.Helper()
+	select {
+	case got := <-r.ops:
+		if got.Op != op || got.Path != pth {
+			t.Fatalf("got %s %s, want %s %s", got.Op, got.Path, op, pth)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no %s %s", op, pth)
+	}
+}
+
+// tick moves fake time by a second until it's at the given offset from start
+func (r *registrar) tick(at time.Duration) {
+	for r.clock.Now().Before(r.start.Add(at)) {
+		r.clock.Advance(time.Second)
+		r.clock.BlockUntilRead()
+	}
+}
+
+func (r *registrar) member(t *testing.T) membership.Member {
+	t.Helper()
+	conn := r.srv.Connect()
+	defer conn.Close()
+	members, err := membership.List(conn, "/members")
+	if err != nil || len(members) != 1 {
+		t.Fatalf("members = %v, %v, want the only one", members, err)
+	}
+	return members[0]
+}
+
+// stop checks that registrar wrote nothing else and unregisters on stop
+func (r *registrar) stop(t *testing.T) {
+	t.Helper()
+	r.cancel()
+	if err := <-r.done; err != nil {
+		t.Fatal(err)
+	}
+	r.waitOp(t, "delete", "/members/node")
+}
+
+func registrarArgs() cmdargs.RunArgs {
+	return cmdargs.RunArgs{
+		NodeID:            "node",
+		ZkACL:             cmdargs.ACLOpen,
+		AttempterTimeout:  time.Second,
+		HeartbeatInterval: 3 * time.Second,
+		MembersDir:        "/members",
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts time.Duration
+	}{
+		{"state checked more often", time.Second},
+		// the first tick comes a bit earlier than heartbeat interval after the first heartbeat
+		{"state checked as often", 3 * time.Second},
+		{"state checked less often", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := registrarArgs()
+			args.AttempterTimeout = tt.attempts
+			r := runRegistrar(t, args)
+			if m := r.member(t); m.HeartbeatInterval != 3*time.Second {
+				t.Fatalf("member heartbeat interval %s, want 3s", m.HeartbeatInterval)
+			}
+
+			for _, at := range []time.Duration{3 * time.Second, 6 * time.Second, 9 * time.Second} {
+				r.tick(at)
+				r.waitOp(t, "set", "/members/node")
+				if m := r.member(t); !m.Heartbeat.Equal(r.start.Add(at)) {
+					t.Fatalf("heartbeat at %s, want at %s", m.Heartbeat.Sub(r.start), at)
+				}
+			}
+			r.stop(t)
+		})
+	}
+}
+
+func TestStaleByWriterInterval(t *testing.T) {
+	r := runRegistrar(t, registrarArgs())
+	r.tick(3 * time.Second)
+	r.waitOp(t, "set", "/members/node")
+	m := r.member(t)
+	r.stop(t)
+
+	// reader of shorter interval doesn't judge writer by its own one
+	now := r.start.Add(3*time.Second + 8*time.Second)
+	if m.Stale(now, time.Second) {
+		t.Fatalf("member is stale %s after heartbeat of 3s interval", now.Sub(m.Heartbeat))
+	}
+	if now = now.Add(2 * time.Second); !m.Stale(now, time.Second) {
+		t.Fatalf("member isn't stale %s after heartbeat of 3s interval", now.Sub(m.Heartbeat))
+	}
+}
+
+func TestMemberData(t *testing.T) {
+	r := runRegistrar(t, registrarArgs())
+	conn := r.srv.Connect()
+	defer conn.Close()
+	data, _, err := conn.Get("/members/node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	// interval is published in nanoseconds as time.Duration marshals
+	if raw["heartbeat_interval"] != float64(3*time.Second) || raw["state"] != membership.StateAttemper || raw["eligible"] != true {
+		t.Fatalf("member node data %s", data)
+	}
+	r.stop(t)
+}
+
+func TestHandoverRequest(t *testing.T) {
+	args := registrarArgs()
+	args.HeartbeatInterval = time.Hour
+	args.Priority = 5
+	args.HandoverAfter = 2 * time.Second
+	r := runRegistrar(t, args)
+	if m := r.member(t); m.Handover || m.Priority != 5 {
+		t.Fatalf("member %+v, want priority 5 without handover request", m)
+	}
+
+	// candidate asks for leadership once it waited for handover-after, it doesn't wait for heartbeat
+	r.tick(2 * time.Second)
+	r.waitOp(t, "set", "/members/node")
+	if m := r.member(t); !m.Handover || !m.Eligible {
+		t.Fatalf("member %+v, want eligible with handover request", m)
+	}
+
+	// paused candidate isn't eligible, so it withdraws the request
+	r.control.Pause()
+	r.tick(3 * time.Second)
+	r.waitOp(t, "set", "/members/node")
+	if m := r.member(t); m.Handover || m.Eligible {
+		t.Fatalf("member %+v, want not eligible without handover request", m)
+	}
+	r.stop(t)
+}
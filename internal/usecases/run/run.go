@@ -4,44 +4,213 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/journal"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/logging"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/metrics"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/tracing"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/attemper_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/leader_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/observer_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ Runner = &LoopRunner{}
 
 type Runner interface {
 	Run(ctx context.Context, state states.AutomataState) error
+	Status() Status
 }
 
-func NewLoopRunner(logger *slog.Logger, metrics *metrics.Metrics) *LoopRunner {
+// Status is a snapshot of the running automaton
+type Status struct {
+	State      string // empty if automaton isn't running
+	Error      string // error automaton stopped with
+	Since      time.Time
+	Previous   string // state automaton came from
+	Reason     string // reason of the last transition
+	Leader     bool
+	Contending bool      // node is leader or attempts to become it
+	Observing  bool      // node follows the election as observer
+	Progress   time.Time // last time running state made progress
+}
+
+// NewLoopRunner creates runner, journal may be nil if transitions aren't journaled.
+// Every state run is traced as a span, it's put to scope so backend calls of the state become its children.
+// On leadership loss runner holds node off contesting through control according to demotion options.
+func NewLoopRunner(logger *slog.Logger, metrics *metrics.Metrics, fields *logging.Fields, journal *journal.Journal, tracer trace.Tracer, scope *tracing.Scope,
+	control *states.Control, opts *cmdargs.Live) *LoopRunner {
 	logger = logger.With("subsystem", "StateRunner")
 	return &LoopRunner{
 		logger:  logger,
 		metrics: metrics,
+		fields:  fields,
+		journal: journal,
+		tracer:  tracer,
+		scope:   scope,
+		control: control,
+		options: opts,
 	}
 }
 
 type LoopRunner struct {
 	logger  *slog.Logger
 	metrics *metrics.Metrics
+	fields  *logging.Fields
+	journal *journal.Journal
+	tracer  trace.Tracer
+	scope   *tracing.Scope
+	control *states.Control
+	options *cmdargs.Live
+
+	progress states.Progress
+
+	mu     sync.RWMutex
+	status Status
 }
 
-func (r *LoopRunner) Run(ctx context.Context, state states.AutomataState) error {
+func (r *LoopRunner) Status() Status {
+	r.mu.RLock()
+	st := r.status
+	r.mu.RUnlock()
+	st.Progress = r.progress.Last()
+	return st
+}
+
+func (r *LoopRunner) setStatus(state states.AutomataState, since time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, isLeader := state.(*leader_s.State)
+	_, isAttemper := state.(*attemper_s.State)
+	_, isObserver := state.(*observer_s.State)
+	r.status = Status{
+		State:      state.String(),
+		Since:      since,
+		Previous:   r.status.State,
+		Reason:     states.Reason(state),
+		Leader:     isLeader,
+		Contending: isLeader || isAttemper,
+		Observing:  isObserver,
+	}
+}
+
+// setStopped records that automaton isn't running, err is nil if it finished without error
+func (r *LoopRunner) setStopped(since time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = Status{Since: since, Previous: r.status.State}
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+}
+
+func (r *LoopRunner) Run(ctx context.Context, state states.AutomataState) (err error) {
+	defer func() { r.setStopped(time.Now(), err) }()
+	ctx = logging.WithFields(ctx, r.fields)
+	ctx = states.WithProgress(ctx, &r.progress)
 	for state != nil {
-		r.logger.LogAttrs(ctx, slog.LevelInfo, "start running state", slog.String("state", state.String()))
+		r.fields.SetState(state.String())
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "start running state")
 		r.metrics.CurState.Set(float64(state.Int()))
 		r.metrics.AmtStateChanges.Inc()
 		r.metrics.CurStateStartTime.SetToCurrentTime()
-		r.metrics.CurStateStartTime.Desc()
+		r.metrics.State.WithLabelValues(state.String()).Set(1)
 
-		var err error
-		state, err = state.Run(ctx)
+		start := time.Now()
+		r.progress.Beat(start)
+		r.setStatus(state, start)
+		next, err := r.runState(ctx, state)
+		r.metrics.StateDuration.WithLabelValues(state.String()).Observe(time.Since(start).Seconds())
+		r.metrics.State.WithLabelValues(state.String()).Set(0)
 		if err != nil {
 			return fmt.Errorf("state %s run: %w", state.String(), err)
 		}
+		if next != nil {
+			r.observeTransition(ctx, state, next)
+		}
+		state = next
 	}
 	r.logger.LogAttrs(ctx, slog.LevelInfo, "no new state, finish")
 	return nil
 }
+
+func (r *LoopRunner) runState(ctx context.Context, state states.AutomataState) (next states.AutomataState, err error) {
+	ctx, span := r.tracer.Start(ctx, state.String(), trace.WithAttributes(
+		attribute.String("election", r.fields.Election()),
+		attribute.String("state", state.String()),
+		attribute.String("reason", states.Reason(state)),
+		attribute.String("zk.session_id", fmt.Sprintf("0x%x", r.fields.SessionID())),
+	))
+	r.scope.Set(ctx)
+	defer func() {
+		if next != nil {
+			span.SetAttributes(attribute.String("next_state", next.String()))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int64("epoch", r.fields.Epoch()))
+		span.End()
+	}()
+	return state.Run(ctx)
+}
+
+func (r *LoopRunner) observeTransition(ctx context.Context, from, to states.AutomataState) {
+	reason := states.Reason(to)
+	r.logger.LogAttrs(ctx, slog.LevelDebug, "state transition", slog.String("from", from.String()), slog.String("to", to.String()), slog.String("reason", reason))
+	r.metrics.StateTransitions.WithLabelValues(from.String(), to.String(), reason).Inc()
+	entry := journal.Entry{
+		Time:      time.Now(),
+		Election:  r.fields.Election(),
+		From:      from.String(),
+		To:        to.String(),
+		Reason:    reason,
+		SessionID: r.fields.SessionID(),
+		Epoch:     r.fields.Epoch(),
+	}
+	if err := r.journal.Append(entry); err != nil {
+		r.logger.LogAttrs(ctx, slog.LevelError, "Failed to journal state transition", slog.String("error", err.Error()))
+	}
+
+	_, wasLeader := from.(*leader_s.State)
+	_, isLeader := to.(*leader_s.State)
+	if !wasLeader && isLeader {
+		r.metrics.LeadershipGained.Inc()
+	} else if wasLeader && !isLeader {
+		r.metrics.LeadershipLost.Inc()
+		r.demote(ctx, to)
+	}
+}
+
+// demote holds node off contesting after leadership loss, it's skipped when automaton stops
+func (r *LoopRunner) demote(ctx context.Context, to states.AutomataState) {
+	if _, stopping := to.(*stopping_s.State); stopping {
+		return
+	}
+	opts := r.options.Get()
+	until, flapping := r.control.Demoted(time.Now(), states.DemotionPolicy{
+		Cooldown:      opts.DemotionCooldown,
+		FlapThreshold: opts.FlapThreshold,
+		FlapWindow:    opts.FlapWindow,
+		FlapBackoff:   opts.FlapBackoff,
+	})
+	if until.IsZero() {
+		return
+	}
+	r.metrics.ContestHoldUntil.Set(float64(until.Unix()))
+	if flapping {
+		r.metrics.LeadershipFlaps.Inc()
+		r.logger.LogAttrs(ctx, slog.LevelWarn, "Leadership flaps, back off contesting",
+			slog.Int("losses", opts.FlapThreshold), slog.Duration("window", opts.FlapWindow), slog.Time("hold_until", until))
+		return
+	}
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "hold off contesting after leadership loss", slog.Time("hold_until", until))
+}
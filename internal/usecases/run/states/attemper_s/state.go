@@ -3,11 +3,14 @@ package attemper_s
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/logging"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/failover_s"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/leader_s"
@@ -15,21 +18,25 @@ import (
 	"github.com/go-zookeeper/zk"
 )
 
-func New(logger *slog.Logger, conn *zk.Conn, ticker ticker.Ticker, opts cmdargs.RunArgs) *State {
+func New(logger *slog.Logger, conn backend.Conn, connector backend.Connector, control *states.Control, ticker ticker.Ticker, opts *cmdargs.Live) *State {
 	logger = logger.With("subsystem", "AttemperState")
 	return &State{
-		logger:  logger,
-		conn:    conn,
-		options: opts,
-		ticker:  ticker,
+		logger:    logger,
+		conn:      conn,
+		options:   opts,
+		connector: connector,
+		control:   control,
+		ticker:    ticker,
 	}
 }
 
 type State struct {
-	logger  *slog.Logger
-	conn    *zk.Conn
-	ticker  ticker.Ticker
-	options cmdargs.RunArgs
+	logger    *slog.Logger
+	conn      backend.Conn
+	connector backend.Connector
+	control   *states.Control
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
 }
 
 func (s *State) String() string {
@@ -40,25 +47,80 @@ func (s *State) Int() int {
 	return 1
 }
 
-func (s *State) SetZkConnection(conn *zk.Conn) {
+func (s *State) SetZkConnection(conn backend.Conn) {
 	s.conn = conn
 }
 
+// setEpoch attaches creation zxid of the created election node to logs and journal, it identifies leadership term
+func (s *State) setEpoch(ctx context.Context, electionPath string) {
+	ok, stat, err := s.conn.Exists(electionPath)
+	if err != nil || !ok {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Failed to read leadership epoch", slog.Bool("exists", ok), slog.Any("error", err))
+		return
+	}
+	logging.FromContext(ctx).SetEpoch(stat.Czxid)
+}
+
+// outranked reports whether there is no leader and a candidate of higher priority is going to become it.
+// Registry errors don't block the election, node contests as if there were no priorities.
+func (s *State) outranked(ctx context.Context, opts cmdargs.RunArgs, now time.Time) (bool, error) {
+	ok, _, err := s.conn.Exists(opts.ElectionFileDir)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "Failed to become leader - already have another one")
+		return true, nil
+	}
+	members, err := membership.List(s.conn, opts.MembersDir)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Failed to read members, priorities are ignored", slog.String("error", err.Error()))
+		return false, nil
+	}
+	if m := membership.Outranking(members, opts.NodeID, opts.Priority, now, opts.HeartbeatInterval); m != nil {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "Defer to candidate of higher priority", slog.String("candidate", m.NodeID), slog.Int("priority", m.Priority))
+		return true, nil
+	}
+	return false, nil
+}
+
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
-	tckr, stTckr := s.ticker.GetTicker(s.options.AttempterTimeout)
-	defer stTckr()
+	logging.FromContext(ctx).SetEpoch(0)
+	opts := s.options.Get()
+	tckr, stTckr := s.ticker.GetTicker(opts.AttempterTimeout)
+	defer func() { stTckr() }()
 	for {
 		select {
-		case <-tckr:
-			if _, err := s.conn.Create(s.options.ElectionFileDir, []byte{}, zk.FlagEphemeral, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
-				s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Got error creating znode: ", err.Error()))
-				return failover_s.New(s.logger, s, err, s.conn, s.ticker, s.options), nil
+		case <-s.options.Changed():
+			if nOpts := s.options.Get(); nOpts.AttempterTimeout != opts.AttempterTimeout {
+				s.logger.LogAttrs(ctx, slog.LevelInfo, "Attemper timeout reloaded", slog.Duration("attempter_timeout", nOpts.AttempterTimeout))
+				stTckr()
+				tckr, stTckr = s.ticker.GetTicker(nOpts.AttempterTimeout)
+			}
+			opts = s.options.Get()
+		case now := <-tckr:
+			states.Beat(ctx)
+			if !s.control.CanContest(now) {
+				s.logger.LogAttrs(ctx, slog.LevelDebug, "Contesting is paused, skip attempt")
+				continue
+			}
+			if skip, err := s.outranked(ctx, opts, now); err != nil {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Got error checking election node", slog.String("error", err.Error()))
+				return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
+			} else if skip {
+				continue
+			}
+			identity := states.Identity{NodeID: opts.NodeID, AdminAddr: opts.AdminAddr, Elected: now}
+			if _, err := s.conn.Create(opts.ElectionFileDir, identity.Marshal(), zk.FlagEphemeral, backend.ACL(opts.ZkACL)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Got error creating znode", slog.String("error", err.Error()))
+				return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
 			} else if errors.Is(err, zk.ErrNodeExists) {
 				s.logger.LogAttrs(ctx, slog.LevelDebug, "Failed to become leader - already have another one")
 				continue
 			} else {
+				s.setEpoch(ctx, opts.ElectionFileDir)
 				s.logger.LogAttrs(ctx, slog.LevelInfo, "Succesfully created file as attemper")
-				return leader_s.New(s.logger, s.conn, s.ticker, s.options), nil
+				return leader_s.New(s.logger, s.conn, s.connector, s.control, s.ticker, s.options, s), nil
 			}
 		case <-ctx.Done():
 			return stopping_s.New(s.logger, s.conn, ctx.Err(), s), nil
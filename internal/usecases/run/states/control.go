@@ -0,0 +1,125 @@
+package states
+
+import (
+	"sync"
+	"time"
+)
+
+// Control lets operator steer contending states: pause attempts to become leader and make leader resign
+type Control struct {
+	mu        sync.Mutex
+	paused    bool
+	holdUntil time.Time
+	resign    chan struct{}
+	losses    []time.Time // leadership losses within flap window
+}
+
+func NewControl() *Control {
+	return &Control{
+		resign: make(chan struct{}, 1),
+	}
+}
+
+// Pause stops attempts to become leader, it doesn't affect current leader
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+	c.holdUntil = time.Time{}
+}
+
+// Resign asks leader to give up leadership and holds node off contesting for cooldown,
+// so that another candidate has a chance to win
+func (c *Control) Resign(now time.Time, cooldown time.Duration) {
+	c.mu.Lock()
+	c.holdUntil = now.Add(cooldown)
+	c.mu.Unlock()
+
+	select {
+	case c.resign <- struct{}{}:
+	default:
+	}
+}
+
+// Resigned returns channel leader listens to for resign requests
+func (c *Control) Resigned() <-chan struct{} {
+	return c.resign
+}
+
+// DropResign forgets resign request which came while node wasn't leader
+func (c *Control) DropResign() {
+	select {
+	case <-c.resign:
+	default:
+	}
+}
+
+// DemotionPolicy says how long node holds off contesting after it lost leadership
+type DemotionPolicy struct {
+	Cooldown time.Duration // after every loss
+	// FlapThreshold losses within FlapWindow make node back off for FlapBackoff, 0 disables flap detection
+	FlapThreshold int
+	FlapWindow    time.Duration
+	FlapBackoff   time.Duration
+}
+
+// Demoted records leadership loss and holds node off contesting according to policy.
+// It returns the end of the hold, zero if node may contest at once, and whether node flaps.
+func (c *Control) Demoted(now time.Time, p DemotionPolicy) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hold := p.Cooldown
+	flapping := false
+	if p.FlapThreshold > 0 {
+		recent := c.losses[:0]
+		for _, t := range c.losses {
+			if now.Sub(t) < p.FlapWindow {
+				recent = append(recent, t)
+			}
+		}
+		c.losses = append(recent, now)
+		if len(c.losses) >= p.FlapThreshold {
+			hold = max(hold, p.FlapBackoff)
+			flapping = true
+			c.losses = nil
+		}
+	}
+	if hold <= 0 {
+		return time.Time{}, false
+	}
+	if until := now.Add(hold); until.After(c.holdUntil) {
+		c.holdUntil = until
+	}
+	return c.holdUntil, flapping
+}
+
+// CanContest reports whether node may try to become leader at the moment
+func (c *Control) CanContest(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.paused && !now.Before(c.holdUntil)
+}
+
+// ControlStatus is a snapshot of Control
+type ControlStatus struct {
+	Paused    bool       `json:"paused"`
+	HoldUntil *time.Time `json:"hold_until,omitempty"` // nil if there is no cooldown after resign
+}
+
+func (c *Control) Status() ControlStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := ControlStatus{Paused: c.paused}
+	if !c.holdUntil.IsZero() {
+		holdUntil := c.holdUntil
+		st.HoldUntil = &holdUntil
+	}
+	return st
+}
@@ -3,24 +3,29 @@ package failover_s
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/tracing"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
 	"github.com/go-zookeeper/zk"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func New(logger *slog.Logger, lastState states.AutomataState, reasonToFail error, conn *zk.Conn, ticker ticker.Ticker, opts cmdargs.RunArgs) *State {
+func New(logger *slog.Logger, lastState states.AutomataState, reasonToFail error, conn backend.Conn, connector backend.Connector, ticker ticker.Ticker, opts *cmdargs.Live) *State {
 	logger = logger.With("subsystem", "FailoverState")
 	return &State{
 		logger:       logger,
 		lastState:    lastState,
 		reasonToFail: reasonToFail,
 		conn:         conn,
+		connector:    connector,
 		ticker:       ticker,
 		options:      opts,
 	}
@@ -30,9 +35,10 @@ type State struct {
 	logger       *slog.Logger
 	lastState    states.AutomataState
 	reasonToFail error
-	conn         *zk.Conn
+	conn         backend.Conn
+	connector    backend.Connector
 	ticker       ticker.Ticker
-	options      cmdargs.RunArgs
+	options      *cmdargs.Live
 }
 
 func (s *State) String() string {
@@ -43,18 +49,34 @@ func (s *State) Int() int {
 	return 3
 }
 
-func (s *State) SetZkConnection(_ *zk.Conn) {}
+func (s *State) SetZkConnection(_ backend.Conn) {}
 
-func (s *State) tryConnect(ctx context.Context) states.AutomataState {
-	conn, _, err := zk.Connect(s.options.ZookeeperServers, s.options.LeaderTimeout)
+func (s *State) tryConnect(ctx context.Context, attempt int) states.AutomataState {
+	ctx, span := tracing.Tracer(ctx).Start(ctx, "failover.retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
+	defer span.End()
+	conn, err := s.connector.Connect(ctx)
 	if err != nil {
-		s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Tried to reconnect failed", err))
+		span.SetStatus(codes.Error, backend.ErrorClass(err))
+		s.logger.LogAttrs(ctx, slog.LevelError, "Tried to reconnect failed", slog.String("error", err.Error()))
 		return nil
 	}
 	s.lastState.SetZkConnection(conn)
 	return s.lastState
 }
 
+// deadLeaderTimeout returns how long zookeeper keeps ephemeral nodes of lost session alive.
+// If not set explicitly it is the session timeout negotiated for that session.
+func (s *State) deadLeaderTimeout() time.Duration {
+	opts := s.options.Get()
+	if opts.MaxDeadLeaderTimeout > 0 {
+		return opts.MaxDeadLeaderTimeout
+	}
+	if s.conn != nil {
+		return s.conn.SessionTimeout()
+	}
+	return opts.SessionTimeout
+}
+
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 	if !errors.Is(s.reasonToFail, zk.ErrConnectionClosed) && !errors.Is(s.reasonToFail, zk.ErrSessionExpired) && !errors.Is(s.reasonToFail, zk.ErrNoServer) {
 		return stopping_s.New(s.logger, s.conn, s.reasonToFail, s.lastState), nil
@@ -63,22 +85,28 @@ func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 		s.conn.Close()
 	}
 
-	tckr, stTckr := s.ticker.GetTicker(s.options.FailoverQuickRetryTimeout)
-	defer stTckr()
-	endQTckr, stTckr := s.ticker.GetTicker(s.options.MaxDeadLeaderTimeout - s.options.FailoverQuickRetryTimeout)
-	defer stTckr()
-	endStateTckr, stTckr := s.ticker.GetTicker(s.options.FailoverMaxStateDuration)
-	defer stTckr()
+	opts := s.options.Get() // failover timings are taken once per run, reloaded ones are used by the next failover
+	tckr, stTckr := s.ticker.GetTicker(opts.FailoverQuickRetryTimeout)
+	defer func() { stTckr() }()
+	deadLeaderTimeout := s.deadLeaderTimeout()
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "Failover started", slog.Duration("dead_leader_timeout", deadLeaderTimeout))
+	endQTckr, stEndQ := s.ticker.GetTimer(max(deadLeaderTimeout-opts.FailoverQuickRetryTimeout, opts.FailoverQuickRetryTimeout))
+	defer stEndQ()
+	endStateTckr, stEndState := s.ticker.GetTimer(opts.FailoverMaxStateDuration)
+	defer stEndState()
 	tckrDur := -1
+	attempt := 0
 	for {
 		select {
 		case <-tckr:
-			if nSt := s.tryConnect(ctx); nSt != nil {
+			attempt++
+			if nSt := s.tryConnect(ctx, attempt); nSt != nil {
 				return nSt, nil
 			}
 			if tckrDur != -1 {
-				tckrDur += int(s.options.FailoverSlowRetryStep)
-				tckr = s.ticker.GetTimer(time.Duration(tckrDur))
+				tckrDur += int(opts.FailoverSlowRetryStep)
+				stTckr()
+				tckr, stTckr = s.ticker.GetTimer(time.Duration(tckrDur))
 			}
 		case <-endQTckr:
 			tckrDur = 0
@@ -91,3 +119,7 @@ func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 		}
 	}
 }
+
+func (s *State) Reason() string {
+	return backend.ErrorClass(s.reasonToFail)
+}
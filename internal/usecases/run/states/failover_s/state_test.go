@@ -0,0 +1,178 @@
+package failover_s
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
+	"github.com/go-zookeeper/zk"
+)
+
+// fakeConnector reports fake time of every connection attempt, it connects once conn is set
+type fakeConnector struct {
+	clock    *ticker.FakeTicker
+	attempts chan time.Time
+	conn     backend.Conn
+}
+
+func (c *fakeConnector) Connect(_ context.Context) (backend.Conn, error) {
+	c.attempts <- c.clock.Now()
+	if c.conn == nil {
+		return nil, zk.ErrNoServer
+	}
+	return c.conn, nil
+}
+
+func (c *fakeConnector) Current() backend.Conn {
+	return c.conn
+}
+
+type fakeConn struct {
+	backend.Conn
+}
+
+// lastState is the state failover returns to
+type lastState struct {
+	conn backend.Conn
+}
+
+func (s *lastState) Run(context.Context) (states.AutomataState, error) { return nil, nil }
+func (s *lastState) SetZkConnection(conn backend.Conn)                 { s.conn = conn }
+func (s *lastState) String() string                                    { return "LastState" }
+func (s *lastState) Int() int                                          { return -1 }
+
+// messages passes log messages to test, so it knows when state handled events without own side effects
+type messages chan string
+
+func (m messages) Enabled(context.Context, slog.Level) bool { return true }
+func (m messages) WithAttrs([]slog.Attr) slog.Handler       { return m }
+func (m messages) WithGroup(string) slog.Handler            { return m }
+func (m messages) Handle(_ context.Context, r slog.Record) error {
+	m <- r.Message
+	return nil
+}
+
+func waitMessage(t *testing.T, msgs messages, want string) {
+	t.Helper()
+	for {
+		select {
+		case msg := <-msgs:
+			if msg == want {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %q log message", want)
+		}
+	}
+}
+
+func waitAttempt(t *testing.T, attempts <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case tm := <-attempts:
+		return tm
+	case <-time.After(time.Second):
+		t.Fatal("no connection attempt")
+		return time.Time{}
+	}
+}
+
+func newState(clock *ticker.FakeTicker, connector *fakeConnector, last *lastState, msgs messages) *State {
+	opts := cmdargs.NewLive(cmdargs.RunArgs{
+		FailoverQuickRetryTimeout: time.Second,
+		FailoverSlowRetryStep:     time.Second,
+		FailoverMaxStateDuration:  20 * time.Second,
+		MaxDeadLeaderTimeout:      4500 * time.Millisecond,
+	})
+	return New(slog.New(msgs), last, zk.ErrSessionExpired, nil, connector, clock, opts)
+}
+
+func TestRetryPhases(t *testing.T) {
+	start := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	clock := ticker.NewFakeTicker(start)
+	connector := &fakeConnector{clock: clock, attempts: make(chan time.Time, 1)}
+	msgs := make(messages, 100)
+	st := newState(clock, connector, &lastState{}, msgs)
+
+	type result struct {
+		next states.AutomataState
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		next, err := st.Run(context.Background())
+		done <- result{next, err}
+	}()
+	clock.BlockUntilCreated(3) // quick ticker, end of quick and end of state timers
+
+	// quick attempts every second until dead leader timeout without one quick retry ends at 3.5s,
+	// then retry interval grows by slow step starting from the next quick attempt
+	want := []time.Duration{1, 2, 3, 4, 5, 7, 10, 14, 19}
+	created := 3
+	for _, at := range want {
+		at *= time.Second
+		if at == 4*time.Second {
+			clock.Advance(start.Add(3500 * time.Millisecond).Sub(clock.Now()))
+			waitMessage(t, msgs, "Failover end quick attempts")
+		}
+		clock.Advance(start.Add(at).Sub(clock.Now()))
+		if got := waitAttempt(t, connector.attempts).Sub(start); got != at {
+			t.Fatalf("connection attempt at %s, want %s", got, at)
+		}
+		if at >= 4*time.Second { // slow attempts replace ticker with timer of the next interval
+			created++
+			clock.BlockUntilCreated(created)
+		}
+	}
+
+	clock.Advance(start.Add(20 * time.Second).Sub(clock.Now()))
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("Run() error = %v", res.err)
+	}
+	if _, ok := res.next.(*stopping_s.State); !ok {
+		t.Fatalf("Run() = %T after max state duration, want stopping state", res.next)
+	}
+	if got, want := states.Reason(res.next), backend.ErrorClass(zk.ErrSessionExpired); got != want {
+		t.Fatalf("stopping reason = %s, want %s", got, want)
+	}
+	select {
+	case tm := <-connector.attempts:
+		t.Fatalf("unexpected connection attempt at %s", tm.Sub(start))
+	default:
+	}
+	if n := clock.Active(); n != 0 {
+		t.Fatalf("%d tickers and timers are left active", n)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	start := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	clock := ticker.NewFakeTicker(start)
+	conn := &fakeConn{}
+	connector := &fakeConnector{clock: clock, attempts: make(chan time.Time, 1), conn: conn}
+	last := &lastState{}
+	st := newState(clock, connector, last, make(messages, 100))
+
+	done := make(chan states.AutomataState, 1)
+	go func() {
+		next, _ := st.Run(context.Background())
+		done <- next
+	}()
+	clock.BlockUntilCreated(3)
+	clock.Advance(time.Second)
+	waitAttempt(t, connector.attempts)
+
+	if next := <-done; next != last {
+		t.Fatalf("Run() = %v, want the last state", next)
+	}
+	if last.conn != conn {
+		t.Fatal("last state didn't get the new connection")
+	}
+}
@@ -0,0 +1,26 @@
+package states
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Identity is written to election node by the leader so others can tell who it is
+type Identity struct {
+	NodeID    string    `json:"node_id"`
+	AdminAddr string    `json:"admin_addr,omitempty"`
+	Elected   time.Time `json:"elected"`
+}
+
+func (i Identity) Marshal() []byte {
+	data, _ := json.Marshal(i) // struct of plain fields can't fail to marshal
+	return data
+}
+
+// ParseIdentity decodes election node data, ok is false for nodes written without identity
+func ParseIdentity(data []byte) (id Identity, ok bool) {
+	if len(data) == 0 || json.Unmarshal(data, &id) != nil || id.NodeID == "" {
+		return Identity{}, false
+	}
+	return id, true
+}
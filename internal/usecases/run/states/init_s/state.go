@@ -2,29 +2,38 @@ package init_s
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/access"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/attemper_s"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/failover_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/observer_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
 	"github.com/go-zookeeper/zk"
 )
 
-func New(logger *slog.Logger, ticker ticker.Ticker, opts cmdargs.RunArgs) *State {
+func New(logger *slog.Logger, connector backend.Connector, control *states.Control, ticker ticker.Ticker, opts *cmdargs.Live) *State {
 	logger = logger.With("subsystem", "InitState")
 	return &State{
-		logger:  logger,
-		options: opts,
-		ticker:  ticker,
+		logger:    logger,
+		options:   opts,
+		connector: connector,
+		control:   control,
+		ticker:    ticker,
 	}
 }
 
 type State struct {
-	logger  *slog.Logger
-	ticker  ticker.Ticker
-	options cmdargs.RunArgs
+	logger    *slog.Logger
+	connector backend.Connector
+	control   *states.Control
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
 }
 
 func (s *State) String() string {
@@ -35,13 +44,48 @@ func (s *State) Int() int {
 	return 0
 }
 
-func (s *State) SetZkConnection(_ *zk.Conn) {}
+func (s *State) SetZkConnection(_ backend.Conn) {}
 
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
-	conn, _, err := zk.Connect(s.options.ZookeeperServers, s.options.LeaderTimeout)
+	conn, err := s.connector.Connect(ctx)
 	if err != nil {
-		s.logger.LogAttrs(ctx, slog.LevelError, err.Error())
-		return failover_s.New(s.logger, attemper_s.New(s.logger, conn, s.ticker, s.options), err, nil, s.ticker, s.options), nil
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to connect to zookeeper", slog.String("error", err.Error()))
+		return failover_s.New(s.logger, s.next(conn), err, nil, s.connector, s.ticker, s.options), nil
 	}
-	return attemper_s.New(s.logger, conn, s.ticker, s.options), nil
+	if err := access.Verify(conn, s.options.Get()); errors.Is(err, access.ErrNoPermission) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Node identity lacks zookeeper permissions", slog.String("error", err.Error()))
+		return stopping_s.New(s.logger, conn, err, s), nil
+	} else if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to verify zookeeper permissions", slog.String("error", err.Error()))
+		return failover_s.New(s.logger, s.next(conn), err, conn, s.connector, s.ticker, s.options), nil
+	}
+	if err := s.createParents(conn); errors.Is(err, zk.ErrNoAuth) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Node identity can't create zookeeper paths", slog.String("error", err.Error()))
+		return stopping_s.New(s.logger, conn, err, s), nil
+	} else if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to create zookeeper paths", slog.String("error", err.Error()))
+		return failover_s.New(s.logger, s.next(conn), err, conn, s.connector, s.ticker, s.options), nil
+	}
+	return s.next(conn), nil
+}
+
+// createParents creates hierarchy of namespace and nested paths, observer only reads and creates nothing
+func (s *State) createParents(conn backend.Conn) error {
+	opts := s.options.Get()
+	if opts.Observer {
+		return nil
+	}
+	paths := []string{opts.ElectionFileDir, opts.LeaderFileDir, opts.MembersDir}
+	if opts.Partitions > 0 {
+		paths = append(paths, opts.PartitionsDir)
+	}
+	return backend.CreateParents(conn, backend.ACL(opts.ZkACL), paths...)
+}
+
+// next returns the state node works in after connection: observer never contends for leadership
+func (s *State) next(conn backend.Conn) states.AutomataState {
+	if s.options.Get().Observer {
+		return observer_s.New(s.logger, conn, s.connector, s.ticker, s.options)
+	}
+	return attemper_s.New(s.logger, conn, s.connector, s.control, s.ticker, s.options)
 }
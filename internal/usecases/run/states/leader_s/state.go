@@ -6,30 +6,40 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/failover_s"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
 	"github.com/go-zookeeper/zk"
 )
 
-func New(logger *slog.Logger, conn *zk.Conn, ticker ticker.Ticker, opts cmdargs.RunArgs) *State {
+// New creates leader state, attemper is the state to return to after resign
+func New(logger *slog.Logger, conn backend.Conn, connector backend.Connector, control *states.Control, ticker ticker.Ticker, opts *cmdargs.Live, attemper states.AutomataState) *State {
 	logger = logger.With("subsystem", "LeaderState")
 	return &State{
-		logger:  logger,
-		conn:    conn,
-		ticker:  ticker,
-		options: opts,
+		logger:    logger,
+		conn:      conn,
+		connector: connector,
+		control:   control,
+		ticker:    ticker,
+		options:   opts,
+		attemper:  attemper,
 	}
 }
 
 type State struct {
-	logger  *slog.Logger
-	conn    *zk.Conn
-	ticker  ticker.Ticker
-	options cmdargs.RunArgs
+	logger    *slog.Logger
+	conn      backend.Conn
+	connector backend.Connector
+	control   *states.Control
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
+	attemper  states.AutomataState
 }
 
 func (s *State) String() string {
@@ -40,12 +50,12 @@ func (s *State) Int() int {
 	return 2
 }
 
-func (s *State) SetZkConnection(conn *zk.Conn) {
+func (s *State) SetZkConnection(conn backend.Conn) {
 	s.conn = conn
 }
 
 func (s *State) checkDataDir(chld []string, stat *zk.Stat) bool {
-	if int(stat.NumChildren) > s.options.StorageCapacity {
+	if int(stat.NumChildren) > s.options.Get().StorageCapacity {
 		return false
 	}
 	for i, ch := range chld {
@@ -58,16 +68,16 @@ func (s *State) checkDataDir(chld []string, stat *zk.Stat) bool {
 
 func (s *State) workWithOldData(ctx context.Context) (int, error) {
 	s.logger.LogAttrs(ctx, slog.LevelDebug, "Leader working with prev leader data")
-	chld, stat, err := s.conn.Children(s.options.LeaderFileDir)
+	chld, stat, err := s.conn.Children(s.options.Get().LeaderFileDir)
 	if err != nil {
-		s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Failed to get info about previous leader dir: ", err.Error()))
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to get info about previous leader dir", slog.String("error", err.Error()))
 		return 0, err
 	}
 	if !s.checkDataDir(chld, stat) { // seems that leaders have different options or smth broken - rm old files as good tone
 		s.logger.LogAttrs(ctx, slog.LevelDebug, "Leader deleting another optioned leader files")
 		for _, fpth := range chld {
-			if err := s.conn.Delete(s.options.LeaderFileDir+"/"+fpth, 0); err != nil {
-				s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Failed to delete children another version folder: ", err.Error()))
+			if err := s.conn.Delete(s.options.Get().LeaderFileDir+"/"+fpth, 0); err != nil {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Failed to delete children another version folder", slog.String("error", err.Error()))
 				return 0, err
 			}
 		}
@@ -79,8 +89,8 @@ func (s *State) workWithOldData(ctx context.Context) (int, error) {
 func (s *State) prepareLeaderFileNode(ctx context.Context) (int, error) {
 	s.logger.LogAttrs(ctx, slog.LevelDebug, "Leader started prepearing its folder")
 	var err error
-	if _, err = s.conn.Create(s.options.LeaderFileDir, []byte{}, 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
-		s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Failed to create leader file dir: ", err.Error()))
+	if _, err = s.conn.Create(s.options.Get().LeaderFileDir, []byte{}, 0, backend.ACL(s.options.Get().ZkACL)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to create leader file dir", slog.String("error", err.Error()))
 		return 0, err
 	} else if errors.Is(err, zk.ErrNodeExists) { // if already exist we should prepare it to work with
 		return s.workWithOldData(ctx)
@@ -88,29 +98,120 @@ func (s *State) prepareLeaderFileNode(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+// resizeStorage keeps leader files named from 0 to capacity-1 after storage capacity is reloaded
+// and returns the number of the next file to write
+func (s *State) resizeStorage(ctx context.Context, fi, oldCap, newCap int) (int, error) {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader storage capacity reloaded", slog.Int("old_capacity", oldCap), slog.Int("new_capacity", newCap))
+	if newCap > oldCap {
+		if fi >= oldCap { // storage is full - fill new files before overwriting old ones
+			return oldCap, nil
+		}
+		return fi, nil
+	}
+	for i := newCap; i < min(fi, oldCap); i++ {
+		if err := s.conn.Delete(s.options.Get().LeaderFileDir+fmt.Sprint("/", i), 0); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			s.logger.LogAttrs(ctx, slog.LevelError, "Failed to delete file out of new capacity", slog.String("error", err.Error()))
+			return 0, err
+		}
+	}
+	return fi, nil
+}
+
+// handoverTo returns candidate of higher priority which asks for leadership, nil if there is none
+func (s *State) handoverTo(ctx context.Context, now time.Time) *membership.Member {
+	opts := s.options.Get()
+	members, err := membership.List(s.conn, opts.MembersDir)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Failed to read members for handover requests", slog.String("error", err.Error()))
+		return nil
+	}
+	m := membership.Outranking(members, opts.NodeID, opts.Priority, now, opts.HeartbeatInterval)
+	if m == nil || !m.Handover {
+		return nil
+	}
+	return m
+}
+
+// resign deletes election node and returns to attemper state. The node is deleted only if it's owned
+// by the session of the state: after session expiry it may be already created by another leader.
+func (s *State) resign(ctx context.Context) states.AutomataState {
+	pth := s.options.Get().ElectionFileDir
+	ok, stat, err := s.conn.Exists(pth)
+	if err == nil && ok {
+		if stat.EphemeralOwner == s.conn.SessionID() {
+			err = s.conn.Delete(pth, stat.Version)
+		} else {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "Election node is owned by another session, leave it", slog.Int64("owner", stat.EphemeralOwner))
+		}
+	}
+	if err != nil && !errors.Is(err, zk.ErrNoNode) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Failed to delete election node on resign", slog.String("error", err.Error()))
+		return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options)
+	}
+	s.attemper.SetZkConnection(s.conn)
+	return s.attemper
+}
+
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
-	tckr, stTckr := s.ticker.GetTicker(s.options.LeaderTimeout)
-	defer stTckr()
+	opts := s.options.Get()
+	elected := time.Now()
+	tckr, stTckr := s.ticker.GetTicker(opts.LeaderTimeout)
+	defer func() { stTckr() }()
+	// handover requests are published with member heartbeats, so there is no point to check them more often
+	hoTckr, stHoTckr := s.ticker.GetTicker(opts.HeartbeatInterval)
+	defer func() { stHoTckr() }()
 
+	s.control.DropResign()
 	fi, err := s.prepareLeaderFileNode(ctx)
 	if err != nil {
-		return failover_s.New(s.logger, s, err, s.conn, s.ticker, s.options), nil
+		return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
 	}
 
-	for ; ; fi++ {
+	for {
 		select {
 		case <-tckr:
-			if fi >= s.options.StorageCapacity {
-				if err := s.conn.Delete(s.options.LeaderFileDir+fmt.Sprint("/", fi%s.options.StorageCapacity), 0); err != nil {
-					s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Failed to delete file: ", err.Error()))
-					return failover_s.New(s.logger, s, err, s.conn, s.ticker, s.options), nil
+			states.Beat(ctx)
+			if fi >= opts.StorageCapacity {
+				if err := s.conn.Delete(opts.LeaderFileDir+fmt.Sprint("/", fi%opts.StorageCapacity), 0); err != nil {
+					s.logger.LogAttrs(ctx, slog.LevelError, "Failed to delete file", slog.String("error", err.Error()))
+					return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
 				}
 			}
-			if _, err := s.conn.Create(s.options.LeaderFileDir+fmt.Sprint("/", fi%s.options.StorageCapacity), []byte{}, 0, zk.WorldACL(zk.PermAll)); err != nil {
-				s.logger.LogAttrs(ctx, slog.LevelError, fmt.Sprint("Failed to create file as leader: ", err.Error()))
-				return failover_s.New(s.logger, s, err, s.conn, s.ticker, s.options), nil
+			if _, err := s.conn.Create(opts.LeaderFileDir+fmt.Sprint("/", fi%opts.StorageCapacity), []byte{}, 0, backend.ACL(opts.ZkACL)); err != nil {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Failed to create file as leader", slog.String("error", err.Error()))
+				return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
 			}
 			s.logger.LogAttrs(ctx, slog.LevelDebug, "Leader created file")
+			fi++
+		case <-s.options.Changed():
+			nOpts := s.options.Get()
+			if nOpts.LeaderTimeout != opts.LeaderTimeout {
+				s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader timeout reloaded", slog.Duration("leader_timeout", nOpts.LeaderTimeout))
+				stTckr()
+				tckr, stTckr = s.ticker.GetTicker(nOpts.LeaderTimeout)
+			}
+			if nOpts.HeartbeatInterval != opts.HeartbeatInterval {
+				stHoTckr()
+				hoTckr, stHoTckr = s.ticker.GetTicker(nOpts.HeartbeatInterval)
+			}
+			if nOpts.StorageCapacity != opts.StorageCapacity {
+				if fi, err = s.resizeStorage(ctx, fi, opts.StorageCapacity, nOpts.StorageCapacity); err != nil {
+					return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options), nil
+				}
+			}
+			opts = nOpts
+		case now := <-hoTckr:
+			if now.Sub(elected) < opts.MinLeaderTenure { // leader doesn't give leadership up voluntarily before tenure ends
+				continue
+			}
+			if m := s.handoverTo(ctx, now); m != nil {
+				s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader hands over to candidate of higher priority",
+					slog.String("candidate", m.NodeID), slog.Int("candidate_priority", m.Priority), slog.Int("priority", opts.Priority))
+				return s.resign(ctx), nil
+			}
+		case <-s.control.Resigned():
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader resigns")
+			return s.resign(ctx), nil
 		case <-ctx.Done():
 			return stopping_s.New(s.logger, s.conn, ctx.Err(), s), nil
 		}
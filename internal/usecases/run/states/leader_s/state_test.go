@@ -0,0 +1,242 @@
+package leader_s
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/membership"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
+	"github.com/go-zookeeper/zk"
+)
+
+var start = time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+
+// attemperState is the state leader returns to after resign
+type attemperState struct {
+	conn backend.Conn
+}
+
+func (s *attemperState) Run(context.Context) (states.AutomataState, error) { return nil, nil }
+func (s *attemperState) SetZkConnection(conn backend.Conn)                 { s.conn = conn }
+func (s *attemperState) String() string                                    { return "AttemperState" }
+func (s *attemperState) Int() int                                          { return 1 }
+
+type result struct {
+	next states.AutomataState
+	err  error
+}
+
+// runLeader starts leader state in new session and waits until it creates its tickers
+func runLeader(t *testing.T, srv *backend.FakeServer, clock *ticker.FakeTicker, args cmdargs.RunArgs) (*backend.FakeConn, context.CancelFunc, <-chan result) {
+	t.Helper()
+	conn := srv.Connect()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := New(logger, conn, &backend.FakeConnector{Server: srv}, states.NewControl(), clock, cmdargs.NewLive(args), &attemperState{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan result, 1)
+	go func() {
+		next, err := st.Run(ctx)
+		done <- result{next, err}
+	}()
+	clock.BlockUntilCreated(2) // leader file and handover tickers
+	return conn, cancel, done
+}
+
+// leaderArgs keep storage of three files written every second
+func leaderArgs() cmdargs.RunArgs {
+	return cmdargs.RunArgs{
+		NodeID:            "node",
+		ZkACL:             cmdargs.ACLOpen,
+		LeaderTimeout:     time.Second,
+		HeartbeatInterval: time.Hour,
+		ElectionFileDir:   "/election",
+		LeaderFileDir:     "/data",
+		MembersDir:        "/members",
+		StorageCapacity:   3,
+	}
+}
+
+func waitOp(t *testing.T, ops <-chan backend.FakeOp, op, pth string) {
+	t.Helper()
+	select {
+	case got := <-ops:
+		if got.Op != op || got.Path != pth {
+			t.Fatalf("got %s %s, want %s %s", got.Op, got.Path, op, pth)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no %s %s", op, pth)
+	}
+}
+
+func stop(t *testing.T, cancel context.CancelFunc, done <-chan result) {
+	t.Helper()
+	cancel()
+	res := <-done
+	if _, ok := res.next.(*stopping_s.State); !ok || res.err != nil {
+		t.Fatalf("leader returned %v, %v, want stopping state", res.next, res.err)
+	}
+}
+
+func TestStorageRotation(t *testing.T) {
+	srv := backend.NewFakeServer()
+	ops := srv.Record()
+	clock := ticker.NewFakeTicker(start)
+	_, cancel, done := runLeader(t, srv, clock, leaderArgs())
+	waitOp(t, ops, "create", "/data")
+
+	// storage is filled first, then the oldest file is overwritten every tick
+	for _, pth := range []string{"/data/0", "/data/1", "/data/2"} {
+		clock.Advance(time.Second)
+		waitOp(t, ops, "create", pth)
+	}
+	for _, pth := range []string{"/data/0", "/data/1", "/data/2", "/data/0"} {
+		clock.Advance(time.Second)
+		waitOp(t, ops, "delete", pth)
+		waitOp(t, ops, "create", pth)
+	}
+	stop(t, cancel, done)
+
+	want := []string{"/data", "/data/0", "/data/1", "/data/2"}
+	if got := srv.Paths(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestStorageOfPreviousLeader(t *testing.T) {
+	srv := backend.NewFakeServer()
+	prev := srv.Connect()
+	for _, pth := range []string{"/data", "/data/0", "/data/1"} {
+		if _, err := prev.Create(pth, nil, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ops := srv.Record()
+	clock := ticker.NewFakeTicker(start)
+	_, cancel, done := runLeader(t, srv, clock, leaderArgs())
+
+	// files of leader with the same capacity are continued
+	clock.Advance(time.Second)
+	waitOp(t, ops, "create", "/data/2")
+	clock.Advance(time.Second)
+	waitOp(t, ops, "delete", "/data/0")
+	waitOp(t, ops, "create", "/data/0")
+	stop(t, cancel, done)
+}
+
+func TestStorageOfLargerCapacity(t *testing.T) {
+	srv := backend.NewFakeServer()
+	prev := srv.Connect()
+	for _, pth := range []string{"/data", "/data/0", "/data/1", "/data/2", "/data/3"} {
+		if _, err := prev.Create(pth, nil, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ops := srv.Record()
+	clock := ticker.NewFakeTicker(start)
+	_, cancel, done := runLeader(t, srv, clock, leaderArgs())
+
+	// files of leader with larger capacity don't fit, they are removed and storage starts over
+	for _, pth := range []string{"/data/0", "/data/1", "/data/2", "/data/3"} {
+		waitOp(t, ops, "delete", pth)
+	}
+	clock.Advance(time.Second)
+	waitOp(t, ops, "create", "/data/0")
+	stop(t, cancel, done)
+}
+
+func TestHandover(t *testing.T) {
+	// leader counts its tenure from the wall clock, fake time mustn't be behind it
+	start := time.Now()
+	fresh := start.Add(3 * time.Second)
+	tests := []struct {
+		name      string
+		candidate membership.Member
+		resign    bool
+	}{
+		{"candidate of higher priority asks", membership.Member{Priority: 1, Eligible: true, Handover: true, Heartbeat: fresh}, true},
+		{"candidate of higher priority doesn't ask", membership.Member{Priority: 1, Eligible: true, Heartbeat: fresh}, false},
+		{"candidate of the same priority asks", membership.Member{Priority: 0, Eligible: true, Handover: true, Heartbeat: fresh}, false},
+		{"paused candidate asks", membership.Member{Priority: 1, Handover: true, Heartbeat: fresh}, false},
+		{"stale candidate asks", membership.Member{Priority: 1, Eligible: true, Handover: true, Heartbeat: start.Add(-time.Minute)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := backend.NewFakeServer()
+			candidate := srv.Connect()
+			m := tt.candidate
+			m.NodeID, m.State, m.HeartbeatInterval = "candidate", membership.StateAttemper, time.Second
+			if err := membership.Register(candidate, "/members", m, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			clock := ticker.NewFakeTicker(start)
+			args := leaderArgs()
+			args.HeartbeatInterval = 3 * time.Second
+			conn, cancel, done := runLeader(t, srv, clock, args)
+			if _, err := conn.Create("/election", nil, zk.FlagEphemeral, nil); err != nil {
+				t.Fatal(err)
+			}
+			// members are checked at the third second, the fourth tick is read after the check is done
+			for range 4 {
+				clock.Advance(time.Second)
+				clock.BlockUntilRead()
+			}
+
+			if !tt.resign {
+				if ok, _, _ := conn.Exists("/election"); !ok {
+					t.Fatal("leader deleted election node")
+				}
+				stop(t, cancel, done)
+				return
+			}
+			defer cancel()
+			res := <-done
+			if next, ok := res.next.(*attemperState); !ok || next.conn != conn {
+				t.Fatalf("leader returned %v, want attemper state with its session", res.next)
+			}
+			if ok, _, _ := conn.Exists("/election"); ok {
+				t.Fatal("election node is left after handover")
+			}
+		})
+	}
+}
+
+func TestResignKeepsNodeOfAnotherSession(t *testing.T) {
+	srv := backend.NewFakeServer()
+	clock := ticker.NewFakeTicker(start)
+	control := states.NewControl()
+	conn := srv.Connect()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := New(logger, conn, &backend.FakeConnector{Server: srv}, control, clock, cmdargs.NewLive(leaderArgs()), &attemperState{})
+
+	// election node is recreated by another leader after session of this one expired
+	other := srv.Connect()
+	if _, err := other.Create("/election", nil, zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan result, 1)
+	go func() {
+		next, err := st.Run(context.Background())
+		done <- result{next, err}
+	}()
+	clock.BlockUntilCreated(2) // resign requested before leader starts is dropped
+	control.Resign(start, 0)
+	if res := <-done; res.err != nil {
+		t.Fatal(res.err)
+	} else if _, ok := res.next.(*attemperState); !ok {
+		t.Fatalf("leader returned %v, want attemper state", res.next)
+	}
+	_, stat, err := other.Exists("/election")
+	if err != nil || stat.EphemeralOwner != other.SessionID() {
+		t.Fatalf("election node of another session is deleted: %v, %v", stat, err)
+	}
+}
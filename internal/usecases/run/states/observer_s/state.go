@@ -0,0 +1,127 @@
+package observer_s
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/inspect"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/failover_s"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping_s"
+	"github.com/go-zookeeper/zk"
+)
+
+// New creates state of node which follows the election with watches and never contends for leadership
+func New(logger *slog.Logger, conn backend.Conn, connector backend.Connector, ticker ticker.Ticker, opts *cmdargs.Live) *State {
+	logger = logger.With("subsystem", "ObserverState")
+	return &State{
+		logger:    logger,
+		conn:      conn,
+		connector: connector,
+		ticker:    ticker,
+		options:   opts,
+	}
+}
+
+type State struct {
+	logger    *slog.Logger
+	conn      backend.Conn
+	connector backend.Connector
+	ticker    ticker.Ticker
+	options   *cmdargs.Live
+
+	leader inspect.LeaderInfo // observed election node
+}
+
+func (s *State) String() string {
+	return "ObserverState"
+}
+
+func (s *State) Int() int {
+	return 5
+}
+
+func (s *State) SetZkConnection(conn backend.Conn) {
+	s.conn = conn
+}
+
+// watchLeader reads election node and sets watch on it
+func (s *State) watchLeader(ctx context.Context) (<-chan zk.Event, error) {
+	pth := s.options.Get().ElectionFileDir
+	_, _, ch, err := s.conn.ExistsW(pth)
+	if err != nil {
+		return nil, err
+	}
+	info, err := inspect.Leader(s.conn, pth)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !info.Present && s.leader.Present:
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Observed leader loss")
+	case info.Present && (info.SessionID != s.leader.SessionID || !info.Created.Equal(s.leader.Created)):
+		nodeID := "unknown"
+		if info.Identity != nil {
+			nodeID = info.Identity.NodeID
+		}
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Observed new leader", slog.String("leader", nodeID), slog.Int64("leader_session_id", info.SessionID))
+	}
+	s.leader = info
+	return ch, nil
+}
+
+// watchRing sets watch on leader files, or on leader dir itself until it's created
+func (s *State) watchRing(ctx context.Context) (<-chan zk.Event, error) {
+	pth := s.options.Get().LeaderFileDir
+	chld, _, ch, err := s.conn.ChildrenW(pth)
+	if errors.Is(err, zk.ErrNoNode) {
+		_, _, ch, err = s.conn.ExistsW(pth)
+		return ch, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "Observed leader files", slog.Int("files", len(chld)))
+	return ch, nil
+}
+
+func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
+	leaderCh, err := s.watchLeader(ctx)
+	if err != nil {
+		return s.fail(ctx, err), nil
+	}
+	ringCh, err := s.watchRing(ctx)
+	if err != nil {
+		return s.fail(ctx, err), nil
+	}
+	for {
+		select {
+		case ev := <-leaderCh:
+			if ev.Type == zk.EventNotWatching {
+				return s.fail(ctx, ev.Err), nil
+			}
+			if leaderCh, err = s.watchLeader(ctx); err != nil {
+				return s.fail(ctx, err), nil
+			}
+		case ev := <-ringCh:
+			if ev.Type == zk.EventNotWatching {
+				return s.fail(ctx, ev.Err), nil
+			}
+			if ringCh, err = s.watchRing(ctx); err != nil {
+				return s.fail(ctx, err), nil
+			}
+		case <-ctx.Done():
+			return stopping_s.New(s.logger, s.conn, ctx.Err(), s), nil
+		}
+	}
+}
+
+func (s *State) fail(ctx context.Context, err error) states.AutomataState {
+	s.logger.LogAttrs(ctx, slog.LevelError, "Failed to watch election", slog.String("error", err.Error()))
+	s.leader = inspect.LeaderInfo{}
+	return failover_s.New(s.logger, s, err, s.conn, s.connector, s.ticker, s.options)
+}
@@ -0,0 +1,39 @@
+package states
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is the time automaton last made progress. States beat it every iteration of their loops,
+// so stale progress means the running state hangs, e.g. on zookeeper call.
+type Progress struct {
+	last atomic.Int64 // unix nano
+}
+
+func (p *Progress) Beat(now time.Time) {
+	p.last.Store(now.UnixNano())
+}
+
+// Last returns the time of the last beat, zero if there were none
+func (p *Progress) Last() time.Time {
+	if last := p.last.Load(); last != 0 {
+		return time.Unix(0, last)
+	}
+	return time.Time{}
+}
+
+type progressKey struct{}
+
+// WithProgress makes progress available to states which get only context
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// Beat records progress of the state running with ctx, it does nothing if runner doesn't track progress
+func Beat(ctx context.Context) {
+	if p, ok := ctx.Value(progressKey{}).(*Progress); ok {
+		p.Beat(time.Now())
+	}
+}
@@ -3,12 +3,25 @@ package states
 import (
 	"context"
 
-	"github.com/go-zookeeper/zk"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 )
 
 type AutomataState interface {
 	Run(context.Context) (AutomataState, error)
-	SetZkConnection(*zk.Conn)
+	SetZkConnection(backend.Conn)
 	String() string
 	Int() int
 }
+
+// Reasoner is implemented by states which know why automaton has come to them
+type Reasoner interface {
+	Reason() string
+}
+
+// Reason returns why automaton switched to the state
+func Reason(state AutomataState) string {
+	if r, ok := state.(Reasoner); ok {
+		return r.Reason()
+	}
+	return "ok"
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
-	"github.com/go-zookeeper/zk"
 )
 
-func New(logger *slog.Logger, conn *zk.Conn, reasonToFail error, lastState states.AutomataState) *State {
+func New(logger *slog.Logger, conn backend.Conn, reasonToFail error, lastState states.AutomataState) *State {
 	logger = logger.With("subsystem", "StoppingState")
 	return &State{
 		logger:       logger,
@@ -20,7 +20,7 @@ func New(logger *slog.Logger, conn *zk.Conn, reasonToFail error, lastState state
 
 type State struct {
 	logger       *slog.Logger
-	conn         *zk.Conn
+	conn         backend.Conn
 	reasonToFail error
 	lastState    states.AutomataState
 }
@@ -33,7 +33,7 @@ func (s *State) Int() int {
 	return 4
 }
 
-func (s *State) SetZkConnection(_ *zk.Conn) {}
+func (s *State) SetZkConnection(_ backend.Conn) {}
 
 func (s *State) Run(_ context.Context) (states.AutomataState, error) {
 	if s.conn != nil {
@@ -42,3 +42,7 @@ func (s *State) Run(_ context.Context) (states.AutomataState, error) {
 
 	return s.lastState, s.reasonToFail
 }
+
+func (s *State) Reason() string {
+	return backend.ErrorClass(s.reasonToFail)
+}
@@ -0,0 +1,201 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/inspect"
+	"github.com/go-zookeeper/zk"
+)
+
+// Event types
+const (
+	EventStart         = "start"
+	EventLeaderElected = "leader_elected"
+	EventLeaderLost    = "leader_lost"
+	EventRingAdvanced  = "ring_advanced"
+)
+
+// retryInterval is the pause before watches are set again after zookeeper error
+const retryInterval = time.Second
+
+// Event is one observed change of the election
+type Event struct {
+	Time   time.Time           `json:"time"`
+	Type   string              `json:"type"`
+	Leader *inspect.LeaderInfo `json:"leader,omitempty"`
+	File   string              `json:"file,omitempty"`
+	// GapSeconds is time without leader, it's set only if watcher saw the previous leader disappear
+	GapSeconds *float64 `json:"gap_seconds,omitempty"`
+}
+
+// Watcher follows election node and leader dir with zookeeper watches
+type Watcher struct {
+	logger *slog.Logger
+	conn   backend.Conn
+	opts   cmdargs.RunArgs
+	ticker ticker.Ticker
+
+	leaderZxid int64 // creation zxid of the current election node, 0 if there is no leader
+	lostAt     time.Time
+	files      map[string]int64 // leader file name to its creation zxid
+}
+
+func New(logger *slog.Logger, conn backend.Conn, opts cmdargs.RunArgs, ticker ticker.Ticker) *Watcher {
+	return &Watcher{
+		logger: logger.With("subsystem", "Watcher"),
+		conn:   conn,
+		opts:   opts,
+		ticker: ticker,
+	}
+}
+
+// Run emits events until ctx is done or emit fails, zookeeper errors are retried
+func (w *Watcher) Run(ctx context.Context, emit func(Event) error) error {
+	leader, leaderCh, err := w.readLeader()
+	if err != nil {
+		return fmt.Errorf("watch election node: %w", err)
+	}
+	ringCh, err := w.readRing(nil)
+	if err != nil {
+		return fmt.Errorf("watch leader dir: %w", err)
+	}
+	if err := emit(Event{Time: time.Now(), Type: EventStart, Leader: &leader}); err != nil {
+		return err
+	}
+
+	var retry <-chan time.Time
+	stRetry := func() {}
+	defer func() { stRetry() }()
+	for {
+		if (leaderCh == nil || ringCh == nil) && retry == nil {
+			retry, stRetry = w.ticker.GetTimer(retryInterval)
+		}
+
+		select {
+		case <-leaderCh:
+			leaderCh = nil
+		case <-ringCh:
+			ringCh = nil
+		case <-retry:
+			retry = nil
+		case <-ctx.Done():
+			return nil
+		}
+
+		if leaderCh == nil {
+			if leaderCh, err = w.leaderChanged(emit); err != nil {
+				return err
+			}
+		}
+		if ringCh == nil {
+			if ringCh, err = w.readRing(emit); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// leaderChanged rereads election node and emits event if leader changed.
+// Zookeeper errors are logged and nil channel is returned, so the watch is set again on retry.
+func (w *Watcher) leaderChanged(emit func(Event) error) (<-chan zk.Event, error) {
+	prevZxid := w.leaderZxid
+	leader, ch, err := w.readLeader()
+	if err != nil {
+		w.logger.Warn("Failed to watch election node", slog.String("error", err.Error()))
+		return nil, nil
+	}
+	if w.leaderZxid == prevZxid {
+		return ch, nil
+	}
+
+	now := time.Now()
+	if !leader.Present {
+		w.lostAt = now
+		return ch, emit(Event{Time: now, Type: EventLeaderLost})
+	}
+	ev := Event{Time: now, Type: EventLeaderElected, Leader: &leader}
+	if prevZxid == 0 && !w.lostAt.IsZero() {
+		gap := now.Sub(w.lostAt).Seconds()
+		ev.GapSeconds = &gap
+	}
+	w.lostAt = time.Time{}
+	return ch, emit(ev)
+}
+
+func (w *Watcher) readLeader() (inspect.LeaderInfo, <-chan zk.Event, error) {
+	ok, stat, ch, err := w.conn.ExistsW(w.opts.ElectionFileDir)
+	if err != nil {
+		return inspect.LeaderInfo{}, nil, err
+	}
+	if !ok {
+		w.leaderZxid = 0
+		return inspect.LeaderInfo{}, ch, nil
+	}
+	// node can be deleted right after exists, then watch fires and it's read again
+	leader, err := inspect.Leader(w.conn, w.opts.ElectionFileDir)
+	if err != nil {
+		return inspect.LeaderInfo{}, nil, err
+	}
+	w.leaderZxid = stat.Czxid
+	if !leader.Present {
+		w.leaderZxid = 0
+	}
+	return leader, ch, nil
+}
+
+// readRing lists leader dir and emits event for every file created since the previous read,
+// nil emit only remembers current files
+func (w *Watcher) readRing(emit func(Event) error) (<-chan zk.Event, error) {
+	chld, _, ch, err := w.conn.ChildrenW(w.opts.LeaderFileDir)
+	if errors.Is(err, zk.ErrNoNode) { // wait for the first leader to create dir
+		var ok bool
+		if ok, _, ch, err = w.conn.ExistsW(w.opts.LeaderFileDir); err == nil && ok { // created in between
+			return w.readRing(emit)
+		}
+	}
+	if err != nil {
+		w.logger.Warn("Failed to watch leader dir", slog.String("error", err.Error()))
+		return nil, nil
+	}
+
+	type created struct {
+		name string
+		zxid int64
+	}
+	var news []created
+	files := make(map[string]int64, len(chld))
+	for _, name := range chld {
+		ok, stat, err := w.conn.Exists(w.opts.LeaderFileDir + "/" + name)
+		if err != nil {
+			w.logger.Warn("Failed to stat leader file", slog.String("file", name), slog.String("error", err.Error()))
+			return nil, nil
+		}
+		if !ok {
+			continue
+		}
+		files[name] = stat.Czxid
+		if zxid, seen := w.files[name]; !seen || zxid != stat.Czxid {
+			news = append(news, created{name, stat.Czxid})
+		}
+	}
+	w.files = files
+	if emit == nil {
+		return ch, nil
+	}
+
+	sort.Slice(news, func(i, j int) bool { return news[i].zxid < news[j].zxid })
+	for _, f := range news {
+		if err := emit(Event{Time: time.Now(), Type: EventRingAdvanced, File: f.name}); err != nil {
+			return nil, err
+		}
+	}
+	return ch, nil
+}
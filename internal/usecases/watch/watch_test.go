@@ -0,0 +1,184 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backend"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticker"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/go-zookeeper/zk"
+)
+
+var args = cmdargs.RunArgs{ElectionFileDir: "/election", LeaderFileDir: "/data"}
+
+// startWatcher runs watcher on conn and passes its events to the returned channel
+func startWatcher(t *testing.T, conn backend.Conn) <-chan Event {
+	t.Helper()
+	events := make(chan Event, 100)
+	w := New(slog.New(slog.NewTextHandler(io.Discard, nil)), conn, args, ticker.NewFakeTicker(time.Now()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(ev Event) error {
+			events <- ev
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("watcher failed: %v", err)
+		}
+	})
+	return events
+}
+
+func waitEvent(t *testing.T, events <-chan Event, typ string) Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		if ev.Type != typ {
+			t.Fatalf("got %s event %+v, want %s", ev.Type, ev, typ)
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("no %s event", typ)
+		return Event{}
+	}
+}
+
+func elect(t *testing.T, srv *backend.FakeServer, nodeID string) *backend.FakeConn {
+	t.Helper()
+	conn := srv.Connect()
+	if _, err := conn.Create(args.ElectionFileDir, states.Identity{NodeID: nodeID}.Marshal(), zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestLeaderElectedAndLost(t *testing.T) {
+	srv := backend.NewFakeServer()
+	events := startWatcher(t, srv.Connect())
+	if ev := waitEvent(t, events, EventStart); ev.Leader.Present {
+		t.Fatalf("start event %+v, want no leader", ev.Leader)
+	}
+
+	first := elect(t, srv, "first")
+	ev := waitEvent(t, events, EventLeaderElected)
+	if ev.Leader.Identity == nil || ev.Leader.Identity.NodeID != "first" || ev.Leader.SessionID != first.SessionID() {
+		t.Fatalf("elected %+v, want the first node", ev.Leader)
+	}
+	if ev.GapSeconds != nil {
+		t.Fatal("gap is reported though watcher didn't see leader disappear")
+	}
+
+	first.Expire()
+	waitEvent(t, events, EventLeaderLost)
+
+	elect(t, srv, "second")
+	ev = waitEvent(t, events, EventLeaderElected)
+	if ev.Leader.Identity == nil || ev.Leader.Identity.NodeID != "second" || ev.GapSeconds == nil {
+		t.Fatalf("elected %+v with gap %v, want the second node with gap", ev.Leader, ev.GapSeconds)
+	}
+}
+
+func TestLeaderPresentAtStart(t *testing.T) {
+	srv := backend.NewFakeServer()
+	first := elect(t, srv, "first")
+	events := startWatcher(t, srv.Connect())
+	waitEvent(t, events, EventStart)
+
+	first.Expire()
+	waitEvent(t, events, EventLeaderLost)
+	elect(t, srv, "second")
+	if ev := waitEvent(t, events, EventLeaderElected); ev.Leader.Identity.NodeID != "second" {
+		t.Fatalf("elected %+v, want the second node", ev.Leader)
+	}
+}
+
+// vanishingConn deletes leader once after election node is found by ExistsW and before it's read
+type vanishingConn struct {
+	*backend.FakeConn
+	mu     sync.Mutex
+	leader *backend.FakeConn
+}
+
+func (c *vanishingConn) Get(path string) ([]byte, *zk.Stat, error) {
+	c.mu.Lock()
+	if path == args.ElectionFileDir && c.leader != nil {
+		c.leader.Expire()
+		c.leader = nil
+	}
+	c.mu.Unlock()
+	return c.FakeConn.Get(path)
+}
+
+func (c *vanishingConn) vanish(leader *backend.FakeConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leader = leader
+}
+
+func TestLeaderVanishedWhileRead(t *testing.T) {
+	srv := backend.NewFakeServer()
+	conn := &vanishingConn{FakeConn: srv.Connect()}
+	events := startWatcher(t, conn)
+	waitEvent(t, events, EventStart)
+
+	// leader deleted before it's read is neither elected nor lost for watcher,
+	// so the next leader is reported as elected and not preceded by a false loss
+	leader := srv.Connect()
+	conn.vanish(leader)
+	if _, err := leader.Create(args.ElectionFileDir, states.Identity{NodeID: "vanished"}.Marshal(), zk.FlagEphemeral, nil); err != nil {
+		t.Fatal(err)
+	}
+	// watcher has read the node once the leader is expired
+	for deadline := time.Now().Add(time.Second); len(srv.Paths()) != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("vanished leader isn't read by watcher")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	elect(t, srv, "next")
+	if ev := waitEvent(t, events, EventLeaderElected); ev.Leader.Identity.NodeID != "next" {
+		t.Fatalf("elected %+v, want the next node", ev.Leader)
+	}
+}
+
+func TestRingAdvanced(t *testing.T) {
+	srv := backend.NewFakeServer()
+	events := startWatcher(t, srv.Connect())
+	waitEvent(t, events, EventStart)
+
+	// leader dir is watched before the first leader creates it
+	leader := srv.Connect()
+	if _, err := leader.Create(args.LeaderFileDir, nil, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"0", "1"} {
+		if _, err := leader.Create(args.LeaderFileDir+"/"+name, nil, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if ev := waitEvent(t, events, EventRingAdvanced); ev.File != name {
+			t.Fatalf("advanced to %s, want %s", ev.File, name)
+		}
+	}
+
+	// overwritten file is new for watcher, its deletion alone is not an event
+	if err := leader.Delete(args.LeaderFileDir+"/0", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leader.Create(args.LeaderFileDir+"/0", nil, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ev := waitEvent(t, events, EventRingAdvanced); ev.File != "0" {
+		t.Fatalf("advanced to %s, want overwritten 0", ev.File)
+	}
+}